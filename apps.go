@@ -0,0 +1,192 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/appc/spec/schema"
+	"github.com/appc/spec/schema/types"
+
+	"github.com/3ofcoins/appc-metadata-client/mdc"
+)
+
+// appSummary is a pod's app, as listed by the apps command
+type appSummary struct {
+	Name      string       `json:"name"`
+	ImageName string       `json:"imageName"`
+	ImageID   string       `json:"imageID"`
+	Labels    types.Labels `json:"labels"`
+}
+
+func newAppSummary(ra *schema.RuntimeApp) appSummary {
+	as := appSummary{
+		Name:    ra.Name.String(),
+		ImageID: ra.Image.ID.String(),
+		Labels:  ra.Image.Labels,
+	}
+	if ra.Image.Name != nil {
+		as.ImageName = ra.Image.Name.String()
+	}
+	if as.Labels == nil {
+		as.Labels = types.Labels{}
+	}
+	return as
+}
+
+// imageSummary summarizes app's image manifest
+type imageSummary struct {
+	Name        string            `json:"name"`
+	Labels      types.Labels      `json:"labels"`
+	Annotations types.Annotations `json:"annotations"`
+	Exec        types.Exec        `json:"exec,omitempty"`
+	User        string            `json:"user,omitempty"`
+	Group       string            `json:"group,omitempty"`
+}
+
+// appDetails is a pod's app, as shown by the app command
+type appDetails struct {
+	appSummary
+	// Annotations are the app's runtime annotations, from the pod
+	// manifest (without the image manifest's annotations)
+	Annotations types.Annotations `json:"annotations"`
+	Mounts      []mdc.Mount       `json:"mounts"`
+	// Image is nil if the image manifest is not available
+	Image *imageSummary `json:"image"`
+}
+
+// podApps returns summaries of all apps in the pod manifest
+func podApps(client *mdc.MDClient) ([]appSummary, error) {
+	pm, err := client.PodManifest()
+	if err != nil {
+		return nil, err
+	}
+	apps := make([]appSummary, len(pm.Apps))
+	for i := range pm.Apps {
+		apps[i] = newAppSummary(&pm.Apps[i])
+	}
+	return apps, nil
+}
+
+// appInfo returns details of the pod's app
+func appInfo(client *mdc.MDClient, name string) (*appDetails, error) {
+	app := client.App(name)
+	ra, err := app.RuntimeApp()
+	if err != nil {
+		return nil, err
+	}
+	if ra == nil {
+		return nil, fmt.Errorf("no app %q in the pod", name)
+	}
+
+	ad := &appDetails{appSummary: newAppSummary(ra), Annotations: ra.Annotations}
+	if ad.Annotations == nil {
+		ad.Annotations = types.Annotations{}
+	}
+	if ad.Mounts, err = app.Mounts(); err != nil {
+		return nil, err
+	}
+	if ad.Mounts == nil {
+		ad.Mounts = []mdc.Mount{}
+	}
+
+	im, err := app.ImageManifest()
+	switch {
+	case mdc.IsNotFound(err):
+	case err != nil:
+		return nil, err
+	default:
+		ad.Image = &imageSummary{
+			Name:        im.Name.String(),
+			Labels:      im.Labels,
+			Annotations: im.Annotations,
+		}
+		if ad.Image.Labels == nil {
+			ad.Image.Labels = types.Labels{}
+		}
+		if ad.Image.Annotations == nil {
+			ad.Image.Annotations = types.Annotations{}
+		}
+		if im.App != nil {
+			ad.Image.Exec = im.App.Exec
+			ad.Image.User = im.App.User
+			ad.Image.Group = im.App.Group
+		}
+	}
+
+	return ad, nil
+}
+
+// formatLabels formats labels as NAME=VALUE list separated by sep
+func formatLabels(labels types.Labels, sep string) string {
+	strs := make([]string, len(labels))
+	for i, label := range labels {
+		strs[i] = label.Name.String() + "=" + label.Value
+	}
+	return strings.Join(strs, sep)
+}
+
+// listApps implements the apps command
+func listApps(client *mdc.MDClient, args []string) {
+	if len(args) > 0 {
+		usage(1)
+	}
+	apps, err := podApps(client)
+	check(err)
+	printResult(apps, func() {
+		for _, as := range apps {
+			imageName := as.ImageName
+			if imageName == "" {
+				imageName = "-"
+			}
+			line := as.Name + " " + imageName + " " + as.ImageID
+			if len(as.Labels) > 0 {
+				line += " " + formatLabels(as.Labels, ",")
+			}
+			fmt.Println(line)
+		}
+	})
+}
+
+// showApp implements the app command
+func showApp(client *mdc.MDClient, args []string) {
+	fs := flag.NewFlagSet("app", flag.ExitOnError)
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		usage(1)
+	}
+
+	ad, err := appInfo(client, fs.Arg(0))
+	check(err)
+	printResult(ad, func() {
+		fmt.Println("name:", ad.Name)
+		fmt.Println("image name:", ad.ImageName)
+		fmt.Println("image ID:", ad.ImageID)
+		fmt.Println("labels:", formatLabels(ad.Labels, " "))
+		fmt.Println("annotations:")
+		for _, ann := range ad.Annotations {
+			fmt.Printf("  %v=%v\n", ann.Name, ann.Value)
+		}
+		fmt.Println("mounts:")
+		for _, mount := range ad.Mounts {
+			fmt.Println(" ", formatMount(mount))
+		}
+		if ad.Image == nil {
+			fmt.Println("image manifest: not available")
+			return
+		}
+		fmt.Println("image manifest:")
+		fmt.Println("  name:", ad.Image.Name)
+		fmt.Println("  labels:", formatLabels(ad.Image.Labels, " "))
+		if len(ad.Image.Exec) > 0 {
+			fmt.Println("  exec:", strings.Join(ad.Image.Exec, " "))
+		}
+		if ad.Image.User != "" || ad.Image.Group != "" {
+			fmt.Printf("  user: %s, group: %s\n", ad.Image.User, ad.Image.Group)
+		}
+		fmt.Println("  annotations:")
+		for _, ann := range ad.Image.Annotations {
+			fmt.Printf("    %v=%v\n", ann.Name, ann.Value)
+		}
+	})
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/appc/spec/schema"
+	"github.com/appc/spec/schema/types"
+
+	"github.com/3ofcoins/appc-metadata-client/mdc"
+)
+
+func TestPodApps(t *testing.T) {
+	apps, err := podApps(newTestClient(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []appSummary{{
+		Name:      "reduce-worker",
+		ImageName: "example.com/reduce-worker",
+		ImageID:   "sha512-8d3fffddf79e9a232ffd19f9ccaa4d6b37a6a243dbe0f23137b108a043d9da13121a9b505c804956b22e93c7f93969f4a7ba8ddea45bf4aab0bebc8f814e0990",
+		Labels:    types.Labels{},
+	}}; !reflect.DeepEqual(apps, expected) {
+		t.Errorf("Invalid apps: %#v", apps)
+	}
+}
+
+func TestAppInfo(t *testing.T) {
+	client := newTestClient(t)
+
+	ad, err := appInfo(client, "reduce-worker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ad.Name != "reduce-worker" || ad.ImageName != "example.com/reduce-worker" {
+		t.Errorf("Invalid app: %#v", ad.appSummary)
+	}
+	if expected := (types.Annotations{{Name: "foo", Value: "baz"}}); !reflect.DeepEqual(ad.Annotations, expected) {
+		t.Errorf("Invalid annotations: %#v", ad.Annotations)
+	}
+	if len(ad.Mounts) != 0 {
+		t.Errorf("Invalid mounts: %#v", ad.Mounts)
+	}
+	if ad.Image != nil {
+		t.Errorf("Expected no image manifest, got: %#v", ad.Image)
+	}
+
+	if _, err := appInfo(client, "nope"); err == nil {
+		t.Error("Expected error for app not in the pod")
+	}
+}
+
+func TestAppInfoImageManifest(t *testing.T) {
+	pm := &schema.PodManifest{}
+	if err := json.Unmarshal([]byte(testPodManifest), pm); err != nil {
+		t.Fatal(err)
+	}
+	im := &schema.ImageManifest{}
+	if err := json.Unmarshal([]byte(`{
+    "acVersion": "0.7.4",
+    "acKind": "ImageManifest",
+    "name": "example.com/reduce-worker",
+    "annotations": [
+        {"name": "foo", "value": "bar"},
+        {"name": "homepage", "value": "https://example.com"}
+    ]
+}`), im); err != nil {
+		t.Fatal(err)
+	}
+	client, err := mdc.NewMDClient(mdc.Options{
+		AppName: "reduce-worker",
+		Service: &mdc.Pod{
+			UUID:           mdc.DefaultPodUUID,
+			Manifest:       pm,
+			ImageManifests: map[string]*schema.ImageManifest{"reduce-worker": im},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ad, err := appInfo(client, "reduce-worker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Runtime annotations don't include the image manifest's ones
+	if expected := (types.Annotations{{Name: "foo", Value: "baz"}}); !reflect.DeepEqual(ad.Annotations, expected) {
+		t.Errorf("Invalid annotations: %#v", ad.Annotations)
+	}
+	if ad.Image == nil {
+		t.Fatal("Expected image manifest")
+	}
+	if !reflect.DeepEqual(ad.Image.Annotations, im.Annotations) {
+		t.Errorf("Invalid image annotations: %#v", ad.Image.Annotations)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/3ofcoins/appc-metadata-client/mdc"
+)
+
+// checkResult is the JSON output of the check command
+type checkResult struct {
+	Annotations []mdc.AnnotationRef `json:"annotations"`
+	Errors      []string            `json:"errors"`
+}
+
+// checkTemplates implements the check command: it lists annotations
+// referenced by templates, without talking to the metadata service,
+// and fails on syntax errors and calls to unknown methods.
+func checkTemplates(args []string) {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	format := fs.String("format", "text", "output `FORMAT`: text or json")
+	fs.Parse(args)
+
+	if fs.NArg() < 1 || (*format != "text" && *format != "json") {
+		usage(1)
+	}
+
+	result := checkResult{Annotations: []mdc.AnnotationRef{}, Errors: []string{}}
+	for _, path := range fs.Args() {
+		tmpl, err := parseTemplates([]string{path}, nil)
+		if err != nil {
+			result.Errors = append(result.Errors, err.Error())
+			continue
+		}
+		refs, errs := mdc.CheckTemplate(tmpl)
+		result.Annotations = append(result.Annotations, refs...)
+		for _, err := range errs {
+			result.Errors = append(result.Errors, err.Error())
+		}
+	}
+
+	if structuredOutput() {
+		printDocument(result)
+	} else if *format == "json" {
+		out, err := json.MarshalIndent(result, "", "  ")
+		check(err)
+		fmt.Println(string(out))
+	} else {
+		for _, ref := range result.Annotations {
+			fmt.Printf("%s: %s annotation %s (%s", ref.Location, ref.Layer, ref.Name, ref.Method)
+			if ref.Default {
+				fmt.Print(", has default")
+			}
+			fmt.Println(")")
+		}
+		for _, msg := range result.Errors {
+			fmt.Fprintln(os.Stderr, "ERROR:", msg)
+		}
+	}
+
+	if len(result.Errors) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/appc/spec/schema/types"
+
+	"github.com/3ofcoins/appc-metadata-client/mdc"
+)
+
+var envVarNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// envVarName converts annotation name to environment variable name:
+// letters are upper-cased, other characters that can't be used in
+// a variable name are replaced with underscores, and a name starting
+// with a digit is prefixed with an underscore.
+func envVarName(name string) string {
+	if name == "" {
+		return ""
+	}
+	rv := []byte(strings.ToUpper(name))
+	for i, c := range rv {
+		if !(c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+			rv[i] = '_'
+		}
+	}
+	if rv[0] >= '0' && rv[0] <= '9' {
+		return "_" + string(rv)
+	}
+	return string(rv)
+}
+
+// envCollisionError is returned by envVars when several annotations
+// map to the same variable name.
+type envCollisionError map[string][]string
+
+func (err envCollisionError) Error() string {
+	vars := make([]string, 0, len(err))
+	for v := range err {
+		vars = append(vars, v)
+	}
+	sort.Strings(vars)
+	msgs := make([]string, len(vars))
+	for i, v := range vars {
+		msgs[i] = fmt.Sprintf("%s (from %s)", v, strings.Join(err[v], ", "))
+	}
+	return "variable name collision: " + strings.Join(msgs, "; ")
+}
+
+// envVars returns annotations with names starting with prefix as
+// a map of environment variables. Variable names are taken from
+// mapping (indexed by full annotation name) or made with envVarName,
+// with the prefix removed if strip is true.
+func envVars(anns types.Annotations, prefix string, strip bool, mapping map[string]string) (map[string]string, error) {
+	vars := make(map[string]string)
+	sources := make(map[string][]string)
+	for _, ann := range anns {
+		name := ann.Name.String()
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		v, mapped := mapping[name]
+		if !mapped {
+			if strip {
+				v = envVarName(strings.TrimPrefix(name, prefix))
+			} else {
+				v = envVarName(name)
+			}
+		}
+		if !envVarNameRe.MatchString(v) {
+			return nil, fmt.Errorf("annotation %s: invalid variable name %q", name, v)
+		}
+		vars[v] = ann.Value
+		sources[v] = append(sources[v], name)
+	}
+
+	collisions := make(envCollisionError)
+	for v, names := range sources {
+		if len(names) > 1 {
+			collisions[v] = names
+		}
+	}
+	if len(collisions) > 0 {
+		return nil, collisions
+	}
+	return vars, nil
+}
+
+// sortedKeys returns names of variables, sorted
+func sortedKeys(vars map[string]string) []string {
+	names := make([]string, 0, len(vars))
+	for v := range vars {
+		names = append(names, v)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// shellQuote quotes s for POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+var (
+	dotenvEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "`", "\\`", "\n", `\n`, "\r", `\r`)
+	// systemd unescapes C-style escapes and expands %-specifiers
+	systemdEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`, `%`, `%%`)
+)
+
+// writeEnv writes variables, sorted by name, in given format: shell
+// (export statements), dotenv, systemd (a unit drop-in setting
+// Environment=), or json.
+func writeEnv(w io.Writer, vars map[string]string, format string) error {
+	if format == "json" {
+		out, err := json.MarshalIndent(vars, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(out))
+		return err
+	}
+
+	if format == "systemd" {
+		if _, err := fmt.Fprintln(w, "[Service]"); err != nil {
+			return err
+		}
+	}
+	for _, v := range sortedKeys(vars) {
+		var err error
+		switch format {
+		case "shell":
+			_, err = fmt.Fprintf(w, "export %s=%s\n", v, shellQuote(vars[v]))
+		case "dotenv":
+			_, err = fmt.Fprintf(w, "%s=\"%s\"\n", v, dotenvEscaper.Replace(vars[v]))
+		case "systemd":
+			_, err = fmt.Fprintf(w, "Environment=\"%s=%s\"\n", v, systemdEscaper.Replace(vars[v]))
+		default:
+			return fmt.Errorf("unknown format %q", format)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportEnv implements the env command: it prints pod's (or current
+// app's) annotations as environment variables.
+func exportEnv(client *mdc.MDClient, args []string) {
+	var mapArgs stringsFlag
+	fs := flag.NewFlagSet("env", flag.ExitOnError)
+	prefix := fs.String("prefix", "", "export only annotations with names starting with `P`")
+	strip := fs.Bool("strip", false, "remove the prefix from variable names")
+	app := fs.Bool("app", false, "export current app's annotations instead of pod's")
+	format := fs.String("format", "shell", "output `FORMAT`: shell, dotenv, systemd, or json")
+	fs.Var(&mapArgs, "map", "export annotation as variable `NAME=VAR` (may be repeated)")
+	fs.Parse(args)
+
+	if fs.NArg() > 0 {
+		usage(1)
+	}
+	switch *format {
+	case "shell", "dotenv", "systemd", "json":
+	default:
+		usage(1)
+	}
+
+	mapping := make(map[string]string)
+	for _, arg := range mapArgs {
+		pieces := strings.SplitN(arg, "=", 2)
+		if len(pieces) != 2 {
+			fatal(fmt.Errorf("-map %q: expected NAME=VAR", arg))
+		}
+		mapping[pieces[0]] = pieces[1]
+	}
+
+	var anns types.Annotations
+	var err error
+	if *app {
+		anns, err = client.AppAnnotations()
+	} else {
+		anns, err = client.PodAnnotations()
+	}
+	check(err)
+
+	vars, err := envVars(anns, *prefix, *strip, mapping)
+	check(err)
+	if structuredOutput() {
+		printDocument(vars)
+		return
+	}
+	check(writeEnv(os.Stdout, vars, *format))
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/appc/spec/schema/types"
+)
+
+func TestEnvVarName(t *testing.T) {
+	for name, expected := range map[string]string{
+		"postgresql/host":       "POSTGRESQL_HOST",
+		"example.com/max-conns": "EXAMPLE_COM_MAX_CONNS",
+		"9p/root":               "_9P_ROOT",
+		"already_VALID":         "ALREADY_VALID",
+		"":                      "",
+	} {
+		if actual := envVarName(name); actual != expected {
+			t.Errorf("envVarName(%q): expected %q, got %q", name, expected, actual)
+		}
+	}
+}
+
+func TestEnvVars(t *testing.T) {
+	anns := types.Annotations{
+		{Name: "ip-address", Value: "10.1.2.3"},
+		{Name: "postgresql/host", Value: "db.example.com"},
+		{Name: "postgresql/port", Value: "5432"},
+	}
+
+	if vars, err := envVars(anns, "", false, nil); err != nil {
+		t.Error(err)
+	} else if expected := map[string]string{
+		"IP_ADDRESS":      "10.1.2.3",
+		"POSTGRESQL_HOST": "db.example.com",
+		"POSTGRESQL_PORT": "5432",
+	}; !reflect.DeepEqual(vars, expected) {
+		t.Errorf("Invalid variables: %#v", vars)
+	}
+
+	if vars, err := envVars(anns, "postgresql/", true, map[string]string{"postgresql/host": "PGHOST"}); err != nil {
+		t.Error(err)
+	} else if expected := map[string]string{
+		"PGHOST": "db.example.com",
+		"PORT":   "5432",
+	}; !reflect.DeepEqual(vars, expected) {
+		t.Errorf("Invalid variables: %#v", vars)
+	}
+
+	if _, err := envVars(anns, "", false, map[string]string{"ip-address": "not valid"}); err == nil {
+		t.Error("Expected error for invalid variable name")
+	}
+
+	anns = append(anns, types.Annotation{Name: "postgresql-host", Value: "other"})
+	if _, err := envVars(anns, "", false, nil); err == nil {
+		t.Error("Expected collision error")
+	} else if cerr, ok := err.(envCollisionError); !ok {
+		t.Errorf("Expected envCollisionError, got: %#v", err)
+	} else if expected := (envCollisionError{
+		"POSTGRESQL_HOST": {"postgresql/host", "postgresql-host"},
+	}); !reflect.DeepEqual(cerr, expected) {
+		t.Errorf("Invalid collisions: %#v", cerr)
+	}
+}
+
+func TestWriteEnv(t *testing.T) {
+	vars := map[string]string{
+		"B": "it's $HOME\n100%",
+		"A": `say "hi"`,
+	}
+	for format, expected := range map[string]string{
+		"shell":   "export A='say \"hi\"'\nexport B='it'\\''s $HOME\n100%'\n",
+		"dotenv":  "A=\"say \\\"hi\\\"\"\nB=\"it's \\$HOME\\n100%\"\n",
+		"systemd": "[Service]\nEnvironment=\"A=say \\\"hi\\\"\"\nEnvironment=\"B=it's $HOME\\n100%%\"\n",
+		"json":    "{\n  \"A\": \"say \\\"hi\\\"\",\n  \"B\": \"it's $HOME\\n100%\"\n}\n",
+	} {
+		var buf bytes.Buffer
+		if err := writeEnv(&buf, vars, format); err != nil {
+			t.Errorf("%s: %v", format, err)
+		} else if buf.String() != expected {
+			t.Errorf("%s: expected %q, got %q", format, expected, buf.String())
+		}
+	}
+
+	if err := writeEnv(&bytes.Buffer{}, vars, "xml"); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"github.com/3ofcoins/appc-metadata-client/mdc"
+)
+
+// execEnv returns environ with vars added; variables that are already
+// set in environ are left alone.
+func execEnv(environ []string, vars map[string]string) []string {
+	rv := append([]string(nil), environ...)
+	set := make(map[string]bool)
+	for _, kv := range environ {
+		set[strings.SplitN(kv, "=", 2)[0]] = true
+	}
+	for _, name := range sortedKeys(vars) {
+		if !set[name] {
+			rv = append(rv, name+"="+vars[name])
+		}
+	}
+	return rv
+}
+
+// execRender renders templates listed in specs, and writes them only
+// if all of them render successfully (in strict mode, without missing
+// annotations).
+func execRender(client *mdc.MDClient, specs []renderSpec, searchPath []string, strict bool) ([]renderResult, error) {
+	var rendered []renderedFile
+	render := func() (err error) {
+		rendered, err = renderFiles(client, specs, searchPath)
+		return err
+	}
+	var err error
+	if strict {
+		err = client.Strict(render)
+	} else {
+		err = render()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return writeFiles(rendered)
+}
+
+// execCommand implements the exec command: it renders templates,
+// then replaces mdc with the command, with annotations added to its
+// environment.
+func execCommand(client *mdc.MDClient, args []string) {
+	var renders, searchPath stringsFlag
+	fs := flag.NewFlagSet("exec", flag.ExitOnError)
+	fs.Var(&renders, "render", "render template `SRC:DEST` before running the command (may be repeated)")
+	fs.Var(&searchPath, "I", "look up included templates in `DIR` (may be repeated)")
+	strict := fs.Bool("strict", false, "fail listing all missing annotations referenced by the templates")
+	envPrefix := fs.String("env-prefix", "", "add pod's annotations with names starting with `P` to the command's environment")
+	fs.Parse(args)
+
+	if fs.NArg() < 1 {
+		usage(1)
+	}
+
+	specs := make([]renderSpec, len(renders))
+	for i, arg := range renders {
+		pieces := strings.SplitN(arg, ":", 2)
+		if len(pieces) != 2 || pieces[0] == "" || pieces[1] == "" {
+			fatal(fmt.Errorf("-render %q: expected SRC:DEST", arg))
+		}
+		specs[i] = renderSpec{Source: pieces[0], Destination: pieces[1]}
+	}
+	results, err := execRender(client, specs, searchPath, *strict)
+	check(err)
+	for _, rr := range results {
+		logf("%v", rr)
+	}
+
+	environ := os.Environ()
+	if *envPrefix != "" {
+		anns, err := client.PodAnnotations()
+		check(err)
+		vars, err := envVars(anns, *envPrefix, false, nil)
+		check(err)
+		environ = execEnv(environ, vars)
+	}
+
+	path, err := exec.LookPath(fs.Arg(0))
+	check(err)
+	logf("exec %s %q", path, fs.Args())
+	check(syscall.Exec(path, fs.Args(), environ))
+}
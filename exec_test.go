@@ -0,0 +1,65 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExecEnv(t *testing.T) {
+	environ := []string{"PATH=/bin:/usr/bin", "POSTGRESQL_HOST=localhost"}
+	vars := map[string]string{
+		"POSTGRESQL_PORT": "5432",
+		"POSTGRESQL_HOST": "db.example.com",
+	}
+	if env, expected := execEnv(environ, vars), []string{
+		"PATH=/bin:/usr/bin",
+		"POSTGRESQL_HOST=localhost",
+		"POSTGRESQL_PORT=5432",
+	}; !reflect.DeepEqual(env, expected) {
+		t.Errorf("Invalid environment: %#v", env)
+	}
+	if environ[1] != "POSTGRESQL_HOST=localhost" || len(environ) != 2 {
+		t.Error("Original environment modified:", environ)
+	}
+}
+
+func TestExecRender(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	writeTestFiles(t, dir, map[string]string{
+		"good.tmpl": `host={{.PodAnnotation "postgresql/host"}}`,
+		"bad.tmpl":  `user={{.PodAnnotation "postgresql/user"}}`,
+	})
+	good := renderSpec{Source: filepath.Join(dir, "good.tmpl"), Destination: filepath.Join(dir, "good.conf")}
+	bad := renderSpec{Source: filepath.Join(dir, "bad.tmpl"), Destination: filepath.Join(dir, "bad.conf")}
+
+	// In strict mode, missing annotation fails before anything is written
+	if _, err := execRender(newTestClient(t), []renderSpec{good, bad}, nil, true); err == nil {
+		t.Error("Missing annotation in strict mode didn't fail")
+	}
+	for _, name := range []string{"good.conf", "bad.conf"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s: expected file not to exist, got: %v", name, err)
+		}
+	}
+
+	if results, err := execRender(newTestClient(t), []renderSpec{good, bad}, nil, false); err != nil {
+		t.Fatal("execRender:", err)
+	} else if expected := []renderResult{{good.Destination, true}, {bad.Destination, true}}; !reflect.DeepEqual(results, expected) {
+		t.Errorf("Invalid results: %#v", results)
+	}
+	for name, expected := range map[string]string{
+		"good.conf": "host=db.example.com",
+		"bad.conf":  "user=",
+	} {
+		if data, err := ioutil.ReadFile(filepath.Join(dir, name)); err != nil {
+			t.Error(err)
+		} else if string(data) != expected {
+			t.Errorf("%s: got %#v, expected %#v", name, string(data), expected)
+		}
+	}
+}
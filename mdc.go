@@ -2,271 +2,620 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"io/ioutil"
-	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
-	"text/template"
+	"time"
 
-	"github.com/appc/spec/schema"
 	"github.com/appc/spec/schema/types"
+
+	"github.com/3ofcoins/appc-metadata-client/mdc"
 )
 
-func usage(rv int) {
+func printUsage() {
 	fmt.Fprintln(os.Stderr, strings.Replace(`Usage:
     $0 uuid                          -- show pod UUID
     $0 annotation NAME [DEFAULT]     -- show pod's annotation
+    $0 annotations [-prefix P] [-app]
+                                     -- list pod's (or current app's) annotations,
+                                        optionally only names starting with P
+    $0 env [-prefix P [-strip]] [-app] [-map NAME=VAR]... [-format FORMAT]
+                                     -- export pod's (or current app's) annotations
+                                        as environment variables; FORMAT is shell
+                                        (default), dotenv, systemd, or json
     $0 manifest                      -- show pod manifest JSON
+    $0 query [-image] EXPR           -- show values selected by EXPR (like .apps[].name
+                                        or .app.ports[?name=="http"].port) from pod
+                                        manifest, or current app's image manifest
+                                        with -image; structures are shown as JSON
+    $0 apps                          -- list pod's apps with image names, IDs, and labels
+    $0 app NAME                      -- show app's runtime annotations, mounts, and
+                                        summary of its image manifest
     $0 image-id                      -- show current app image ID
     $0 image-manifest                -- show current app image manifest JSON
     $0 app-annotation NAME [DEFAULT] -- show current app's annotation
-    $0 render PATH|-                 -- render template file or stdin to stdout
-    $0 expand TEMPLATE-STRING        -- render template string to stdout`,
+    $0 lookup [-explain] NAME [DEFAULT]
+                                     -- show annotation of current app, pod, or
+                                        image manifest, first found in lookup order;
+                                        with -explain, show value in each layer
+    $0 environment [-manifest]       -- show current app's environment defined by
+                                        image and pod manifests, with values from
+                                        the process environment (unless -manifest)
+    $0 port [-host] [NAME]           -- show number (or exposed host port) of current
+                                        app's port NAME, or list all ports
+    $0 mounts                        -- list current app's mount points with volumes
+                                        backing them
+    $0 sign CONTENT|-                -- sign content (or stdin) with pod's identity
+    $0 verify UUID SIGNATURE CONTENT|-
+                                     -- verify signature of content made by pod UUID
+    $0 wait [-timeout D] [-interval D] [-annotation NAME]... [-app-annotation NAME]...
+                                     -- wait until metadata and listed annotations
+                                        are available; exit code 3 on timeout
+    $0 serve -pod-manifest FILE [-image-manifest APP=FILE]... [-uuid UUID] [-listen ADDR]
+                                     -- run a metadata service for development
+    $0 render [OPTIONS] [-t NAME] PATH|-...
+                                     -- render template file or stdin to stdout;
+                                        with several files, render the first one
+                                        or template NAME defined in them
+    $0 expand [OPTIONS] TEMPLATE-STRING
+                                     -- render template string to stdout
+       OPTIONS: [-I DIR]... look up included templates in DIR
+                [-strict] fail listing all missing annotations, instead of
+                rendering them as empty strings or failing on the first one
+                [-o PATH [-mode MODE] [-owner USER[:GROUP]]] write output
+                atomically to PATH, reporting "updated" or "unchanged"
+    $0 render-all [-I DIR]... SPEC   -- render templates to files listed in SPEC
+    $0 exec [-render SRC:DEST]... [-I DIR]... [-strict] [-env-prefix P] [--] CMD [ARG]...
+                                     -- render templates to files, then replace mdc
+                                        with CMD, adding pod's annotations starting
+                                        with P to its environment
+    $0 check [-format text|json] TEMPLATE...
+                                     -- list annotations used by templates, fail on
+                                        syntax errors and unknown methods`,
 		"$0", filepath.Base(os.Args[0]), -1))
+	fmt.Fprintln(os.Stderr, "\nOptions (must precede the command):")
+	flag.PrintDefaults()
+}
+
+func usage(rv int) {
+	printUsage()
 	os.Exit(rv)
 	panic("CAN'T HAPPEN")
 }
 
-type MDClient struct {
-	ACMetadataURL, ACAppName              string
-	uuid, appImageID                      string
-	podAnnotations, appAnnotations        types.Annotations
-	podManifestJSON, appImageManifestJSON []byte
-	podManifest                           *schema.PodManifest
-	appImageManifest                      *schema.ImageManifest
+var verbose bool
+
+func logf(format string, v ...interface{}) {
+	if verbose {
+		fmt.Fprintf(os.Stderr, "mdc: "+format+"\n", v...)
+	}
 }
 
-func NewMDClient() *MDClient {
-	rv := &MDClient{
-		ACMetadataURL: os.Getenv("AC_METADATA_URL"),
-		ACAppName:     os.Getenv("AC_APP_NAME"),
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "ERROR:", err)
+	if serr, ok := err.(*mdc.StatusError); ok && len(serr.Body) > 0 {
+		os.Stderr.Write(serr.Body)
+		fmt.Fprintln(os.Stderr)
 	}
+	os.Exit(1)
+}
 
-	if rv.ACMetadataURL == "" {
-		fmt.Fprintln(os.Stderr, "FATAL: No AC_METADATA_URL environment variable")
-		os.Exit(1)
+func check(err error) {
+	if err != nil {
+		fatal(err)
 	}
+}
 
-	if rv.ACAppName == "" {
-		fmt.Fprintln(os.Stderr, "FATAL: No AC_APP_NAME environment variable")
-		os.Exit(1)
+// printString prints s, or in JSON or YAML output format, an object
+// with s as field key
+func printString(key, s string, err error) {
+	check(err)
+	printResult(map[string]string{key: s}, func() { fmt.Println(s) })
+}
+
+// printManifest prints manifest JSON as returned by the metadata
+// service, or in JSON or YAML output format, re-serialized parsed
+// manifest
+func printManifest(raw string, err error, parse func() (interface{}, error)) {
+	check(err)
+	if !structuredOutput() {
+		fmt.Println(raw)
+		return
 	}
+	manifest, err := parse()
+	check(err)
+	printDocument(manifest)
+}
 
-	return rv
+// annotationResult is the JSON or YAML output of annotation commands
+type annotationResult struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	// Found is false if the default value is used
+	Found bool `json:"found"`
+	// Layer is where lookup found the annotation
+	Layer string `json:"layer,omitempty"`
+	// Layers are values in each layer, with lookup -explain
+	Layers []mdc.LayerValue `json:"layers,omitempty"`
 }
 
-func (mdc *MDClient) Get(path string) []byte {
-	req, err := http.NewRequest("GET", mdc.ACMetadataURL+"/acMetadata/v1/"+path, nil)
-	if err != nil {
-		panic(err)
+// printAnnotation prints annotation's value, or default value from
+// args if annotation is not found
+func printAnnotation(anns types.Annotations, err error, args []string) {
+	check(err)
+	result := annotationResult{Name: args[0]}
+	result.Value, result.Found = anns.Get(args[0])
+	if !result.Found && len(args) > 1 {
+		result.Value = args[1]
 	}
-	req.Header.Add("Metadata-Flavor", "AppContainer")
+	printResult(result, func() {
+		if result.Found || len(args) > 1 {
+			fmt.Println(result.Value)
+		}
+	})
+	if !result.Found && len(args) < 2 {
+		fatal(&mdc.AnnotationNotFoundError{Name: args[0]})
+	}
+}
 
-	if resp, err := (&http.Client{}).Do(req); err != nil {
-		panic(err)
-	} else if resp.StatusCode == 404 {
-		return nil
-	} else if resp.StatusCode != 200 {
-		fmt.Fprintln(os.Stderr, "\nERROR: GET", path)
-		resp.Write(os.Stderr)
-		os.Exit(1)
-	} else if body, err := ioutil.ReadAll(resp.Body); err != nil {
-		panic(err)
+func listAnnotations(client *mdc.MDClient, args []string) {
+	fs := flag.NewFlagSet("annotations", flag.ExitOnError)
+	prefix := fs.String("prefix", "", "list only annotations with names starting with `P`")
+	app := fs.Bool("app", false, "list current app's annotations instead of pod's")
+	fs.Parse(args)
+
+	var anns types.Annotations
+	var err error
+	if *app {
+		anns, err = client.AppAnnotations()
 	} else {
-		return body
+		anns, err = client.PodAnnotations()
 	}
+	check(err)
 
-	panic("CAN'T HAPPEN")
-}
-
-func (mdc *MDClient) UUID() string {
-	if mdc.uuid == "" {
-		mdc.uuid = strings.TrimSpace(string(mdc.Get("pod/uuid")))
+	listed := types.Annotations{}
+	for _, ann := range anns {
+		if strings.HasPrefix(ann.Name.String(), *prefix) {
+			listed = append(listed, ann)
+		}
 	}
-	return mdc.uuid
+
+	printResult(listed, func() {
+		for _, ann := range listed {
+			fmt.Printf("%v=%v\n", ann.Name, ann.Value)
+		}
+	})
 }
 
-func (mdc *MDClient) PodAnnotations() types.Annotations {
-	if mdc.podAnnotations == nil {
-		if err := json.Unmarshal(mdc.Get("pod/annotations"), &mdc.podAnnotations); err != nil {
-			panic(err)
+// lookup prints annotation looked up in app, pod, and image manifest
+// layers, or with -explain, annotation's value in each layer.
+func lookup(client *mdc.MDClient, args []string) {
+	fs := flag.NewFlagSet("lookup", flag.ExitOnError)
+	explain := fs.Bool("explain", false, "show annotation's value in each layer and which one is used")
+	fs.Parse(args)
+
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		usage(1)
+	}
+	result := annotationResult{Name: fs.Arg(0)}
+
+	var err error
+	result.Value, result.Layer, err = client.Lookup(result.Name)
+	if _, notFound := err.(*mdc.AnnotationNotFoundError); notFound {
+		err = nil
+		if fs.NArg() > 1 {
+			result.Value = fs.Arg(1)
 		}
+	} else {
+		result.Found = true
 	}
-	return mdc.podAnnotations
-}
+	check(err)
 
-func (mdc *MDClient) PodAnnotation(name string) string {
-	v, _ := mdc.PodAnnotations().Get(name)
-	return v
-}
+	if *explain {
+		result.Layers, err = client.LookupLayers(result.Name)
+		check(err)
+	}
 
-func (mdc *MDClient) HasPodAnnotation(name string) bool {
-	_, v := mdc.PodAnnotations().Get(name)
-	return v
-}
+	printResult(result, func() {
+		if !*explain {
+			if result.Found || fs.NArg() > 1 {
+				fmt.Println(result.Value)
+			}
+			return
+		}
+		for _, lv := range result.Layers {
+			switch {
+			case !lv.Found:
+				fmt.Printf("%s: not set\n", lv.Layer)
+			case lv.Layer != result.Layer:
+				fmt.Printf("%s: %q (overridden)\n", lv.Layer, lv.Value)
+			default:
+				fmt.Printf("%s: %q (used)\n", lv.Layer, lv.Value)
+			}
+		}
+		if !result.Found && fs.NArg() > 1 {
+			fmt.Printf("default: %q (used)\n", result.Value)
+		}
+	})
 
-func (mdc *MDClient) MustPodAnnotation(name string) string {
-	v, found := mdc.PodAnnotations().Get(name)
-	if !found {
-		panic("Annotation not found: " + name)
+	if !result.Found && fs.NArg() < 2 {
+		fatal(&mdc.AnnotationNotFoundError{Name: result.Name})
 	}
-	return v
 }
 
-func (mdc *MDClient) PodAnnotationOr(name, defaultValue string) string {
-	v, found := mdc.PodAnnotations().Get(name)
-	if !found {
-		return defaultValue
+// printEnvironment prints current app's effective (or with -manifest,
+// manifest-defined) environment as NAME=VALUE lines
+func printEnvironment(client *mdc.MDClient, args []string) {
+	fs := flag.NewFlagSet("environment", flag.ExitOnError)
+	manifest := fs.Bool("manifest", false, "ignore the process environment")
+	fs.Parse(args)
+
+	var env types.Environment
+	var err error
+	if *manifest {
+		env, err = client.App(client.ACAppName).ManifestEnvironment()
+	} else {
+		env, err = client.Environment()
 	}
-	return v
-}
+	check(err)
 
-func (mdc *MDClient) podManifestBytes() []byte {
-	if mdc.podManifestJSON == nil {
-		mdc.podManifestJSON = mdc.Get("pod/manifest")
+	if env == nil {
+		env = types.Environment{}
 	}
-	return mdc.podManifestJSON
+	printResult(env, func() {
+		for _, ev := range env {
+			fmt.Printf("%s=%s\n", ev.Name, ev.Value)
+		}
+	})
 }
 
-func (mdc *MDClient) PodManifestJSON() string {
-	return string(mdc.podManifestBytes())
+// formatPort describes port on a single line
+func formatPort(port mdc.Port) string {
+	s := fmt.Sprintf("%s %d/%s", port.Name, port.Port, port.Protocol)
+	if port.Count > 1 {
+		s += fmt.Sprintf(" count=%d", port.Count)
+	}
+	if port.SocketActivated {
+		s += " socket-activated"
+	}
+	if port.HostPort != 0 {
+		s += fmt.Sprintf(" host=%d", port.HostPort)
+	}
+	return s
 }
 
-func (mdc *MDClient) PodManifest() *schema.PodManifest {
-	if mdc.podManifest == nil {
-		mdc.podManifest = &schema.PodManifest{}
-		if err := json.Unmarshal(mdc.podManifestBytes(), mdc.podManifest); err != nil {
-			panic(err)
+// printPorts prints number (or with -host, exposed host port) of
+// current app's port NAME, or lists all ports if no name is given
+func printPorts(client *mdc.MDClient, args []string) {
+	fs := flag.NewFlagSet("port", flag.ExitOnError)
+	host := fs.Bool("host", false, "show host port the port is exposed on")
+	fs.Parse(args)
+
+	switch fs.NArg() {
+	case 0:
+		ports, err := client.Ports()
+		check(err)
+		if ports == nil {
+			ports = []mdc.Port{}
+		}
+		printResult(ports, func() {
+			for _, port := range ports {
+				fmt.Println(formatPort(port))
+			}
+		})
+	case 1:
+		port, err := client.PortInfo(fs.Arg(0))
+		check(err)
+		if structuredOutput() {
+			printDocument(port)
+		} else if !*host {
+			fmt.Println(port.Port)
+		} else if port.HostPort != 0 {
+			fmt.Println(port.HostPort)
+		} else {
+			fatal(fmt.Errorf("port not exposed: %s", port.Name))
 		}
+	default:
+		usage(1)
 	}
-	return mdc.podManifest
 }
 
-func (mdc *MDClient) AppImageID() string {
-	if mdc.appImageID == "" {
-		mdc.appImageID = strings.TrimSpace(string(mdc.Get("apps/" + mdc.ACAppName + "/image/id")))
+// query prints values selected by query expression from pod manifest
+// or current app's image manifest: scalars as plain text, objects and
+// arrays as JSON
+func query(client *mdc.MDClient, args []string) {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	image := fs.Bool("image", false, "query current app's image manifest instead of pod manifest")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		usage(1)
 	}
-	return mdc.appImageID
-}
 
-func (mdc *MDClient) appImageManifestBytes() []byte {
-	if mdc.appImageManifestJSON == nil {
-		mdc.appImageManifestJSON = mdc.Get("apps/" + mdc.ACAppName + "/image/manifest")
+	var values []interface{}
+	var err error
+	if *image {
+		values, err = client.QueryAppImageManifest(fs.Arg(0))
+	} else {
+		values, err = client.QueryPodManifest(fs.Arg(0))
 	}
-	return mdc.appImageManifestJSON
-}
+	check(err)
 
-func (mdc *MDClient) AppImageManifestJSON() string {
-	return string(mdc.appImageManifestBytes())
+	if structuredOutput() {
+		if values == nil {
+			values = []interface{}{}
+		}
+		printDocument(values)
+		return
+	}
+
+	for _, v := range values {
+		switch v := v.(type) {
+		case string:
+			fmt.Println(v)
+		case map[string]interface{}, []interface{}:
+			out, err := json.MarshalIndent(v, "", "  ")
+			check(err)
+			fmt.Println(string(out))
+		default:
+			out, err := json.Marshal(v)
+			check(err)
+			fmt.Println(string(out))
+		}
+	}
 }
 
-func (mdc *MDClient) AppImageManifest() *schema.ImageManifest {
-	if mdc.appImageManifest == nil {
-		mdc.appImageManifest = &schema.ImageManifest{}
-		if err := json.Unmarshal(mdc.appImageManifestBytes(), mdc.appImageManifest); err != nil {
-			panic(err)
+// formatMount describes mount point and its volume on a single line
+func formatMount(mount mdc.Mount) string {
+	s := mount.Name + " " + mount.Path
+	if vol := mount.Volume; vol != nil {
+		s += fmt.Sprintf(" volume=%s kind=%s", vol.Name, vol.Kind)
+		if vol.Source != "" {
+			s += " source=" + vol.Source
 		}
+	} else {
+		s += " no-volume"
+	}
+	if mount.ReadOnly {
+		s += " ro"
 	}
-	return mdc.appImageManifest
+	return s
 }
 
-func (mdc *MDClient) AppAnnotations() types.Annotations {
-	if mdc.appAnnotations == nil {
-		if err := json.Unmarshal(mdc.Get("apps/"+mdc.ACAppName+"/annotations"), &mdc.appAnnotations); err != nil {
-			panic(err)
+// printMounts lists current app's mount points with volumes backing
+// them
+func printMounts(client *mdc.MDClient, args []string) {
+	if len(args) > 0 {
+		usage(1)
+	}
+	mounts, err := client.Mounts()
+	check(err)
+	if structuredOutput() {
+		if mounts == nil {
+			mounts = []mdc.Mount{}
 		}
+		printDocument(mounts)
+		return
+	}
+	for _, mount := range mounts {
+		fmt.Println(formatMount(mount))
 	}
-	return mdc.appAnnotations
 }
 
-func (mdc *MDClient) AppAnnotation(name string) string {
-	v, _ := mdc.AppAnnotations().Get(name)
-	return v
+// lookupOrderFlag is a flag.Value setting annotation lookup order
+type lookupOrderFlag []string
+
+func (lf *lookupOrderFlag) String() string {
+	return strings.Join(*lf, ",")
+}
+
+func (lf *lookupOrderFlag) Set(value string) (err error) {
+	*lf, err = mdc.ParseLookupOrder(value)
+	return
 }
 
-func (mdc *MDClient) HasAppAnnotation(name string) bool {
-	_, v := mdc.AppAnnotations().Get(name)
-	return v
+// stringsFlag is a flag.Value that can be given multiple times
+type stringsFlag []string
+
+func (sf *stringsFlag) String() string {
+	return strings.Join(*sf, ",")
 }
 
-func (mdc *MDClient) MustAppAnnotation(name string) string {
-	v, found := mdc.AppAnnotations().Get(name)
-	if !found {
-		panic("Annotation not found: " + name)
+func (sf *stringsFlag) Set(value string) error {
+	*sf = append(*sf, value)
+	return nil
+}
+
+const exitWaitTimeout = 3
+
+func wait(client *mdc.MDClient, args []string) {
+	var podAnnotations, appAnnotations stringsFlag
+	fs := flag.NewFlagSet("wait", flag.ExitOnError)
+	timeout := fs.Duration("timeout", time.Minute, "give up after `duration`")
+	interval := fs.Duration("interval", 500*time.Millisecond, "poll metadata service every `duration`")
+	fs.Var(&podAnnotations, "annotation", "wait for pod annotation `NAME` (may be repeated)")
+	fs.Var(&appAnnotations, "app-annotation", "wait for app annotation `NAME` (may be repeated)")
+	fs.Parse(args)
+
+	if err := client.Wait(*timeout, *interval, podAnnotations, appAnnotations); err != nil {
+		if werr, ok := err.(*mdc.WaitError); ok {
+			fmt.Fprintln(os.Stderr, "ERROR:", werr)
+			os.Exit(exitWaitTimeout)
+		}
+		fatal(err)
+	}
+	if structuredOutput() {
+		printDocument(map[string]bool{"ready": true})
 	}
-	return v
 }
 
-func (mdc *MDClient) AppAnnotationOr(name, defaultValue string) string {
-	v, found := mdc.AppAnnotations().Get(name)
-	if !found {
-		return defaultValue
+// readArg returns the argument, or standard input contents if arg is "-"
+func readArg(arg string) string {
+	if arg != "-" {
+		return arg
 	}
-	return v
+	data, err := ioutil.ReadAll(os.Stdin)
+	check(err)
+	return string(data)
 }
 
-func main() {
-	mdc := NewMDClient()
+// loadOffline sets opts up to read metadata from files in dir. If
+// neither opts nor the files name the current app, app named by the
+// -app option is the current one.
+func loadOffline(opts *mdc.Options, dir, appName string) error {
+	err := opts.LoadOffline(dir)
+	if err == mdc.ErrNoAppName && appName != "" {
+		opts.AppName = appName
+		err = opts.LoadOffline(dir)
+	}
+	return err
+}
 
-	if len(os.Args) < 2 {
+func main() {
+	opts, envErr := mdc.OptionsFromEnv()
+
+	flag.Usage = printUsage
+	flag.DurationVar(&opts.Retry.Deadline, "deadline", opts.Retry.Deadline,
+		"stop retrying metadata requests `duration` after the first one ($MDC_DEADLINE)")
+	flag.DurationVar(&opts.Retry.Timeout, "timeout", opts.Retry.Timeout,
+		"timeout of a single HTTP request ($MDC_TIMEOUT)")
+	flag.DurationVar(&opts.Retry.Backoff, "backoff", opts.Retry.Backoff,
+		"initial delay between retries ($MDC_BACKOFF)")
+	flag.DurationVar(&opts.Retry.MaxBackoff, "max-backoff", opts.Retry.MaxBackoff,
+		"maximum delay between retries ($MDC_MAX_BACKOFF)")
+	flag.BoolVar(&verbose, "v", false, "report retry policy and retried requests on stderr")
+	appName := flag.String("app", "",
+		"show image and annotations of app `NAME` instead of current app ($AC_APP_NAME)")
+	flag.Var((*lookupOrderFlag)(&opts.LookupOrder), "lookup-order",
+		"comma-separated `LAYERS` searched by lookup, in order of precedence (default app,pod,image; $MDC_LOOKUP_ORDER)")
+	flag.Var(outputFormatFlag{}, "output",
+		"print results as `FORMAT`: text (default), or json or yaml documents")
+	offline := flag.String("offline", "",
+		"read metadata from manifest files in `DIR` instead of metadata service ($MDC_OFFLINE)")
+	flag.Parse()
+	args := flag.Args()
+
+	if len(args) < 1 {
 		usage(0)
 	}
 
-	switch os.Args[1] {
-	case "help", "--help", "-help", "-h":
+	switch args[0] {
+	case "help":
 		usage(0)
+	case "serve":
+		serve(args[1:])
+		return
+	case "check":
+		checkTemplates(args[1:])
+		return
+	}
+
+	if envErr == mdc.ErrNoAppName {
+		// MDC_OFFLINE is set, but current app is not known yet: it
+		// may be given by -app
+		envErr = nil
+		if *offline == "" {
+			*offline = os.Getenv("MDC_OFFLINE")
+		}
+	}
+	if envErr != nil {
+		fmt.Fprintln(os.Stderr, "FATAL:", envErr)
+		os.Exit(1)
+	}
+
+	if *offline != "" {
+		if err := loadOffline(&opts, *offline, *appName); err != nil {
+			fmt.Fprintln(os.Stderr, "FATAL:", err)
+			os.Exit(1)
+		}
+	}
+
+	if *appName != "" {
+		opts.AppName = *appName
+	}
+
+	opts.Logf = logf
+	logf("retry policy: %v", opts.Retry)
+
+	client, err := mdc.NewMDClient(opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "FATAL:", err)
+		os.Exit(1)
+	}
+
+	switch args[0] {
 	case "uuid":
-		fmt.Println(mdc.UUID())
+		uuid, err := client.UUID()
+		printString("uuid", uuid, err)
 	case "annotation":
-		if len(os.Args) < 3 {
+		if len(args) < 2 {
 			usage(1)
 		}
-		if ann, found := mdc.PodAnnotations().Get(os.Args[2]); found {
-			fmt.Println(ann)
-		} else if len(os.Args) > 3 {
-			fmt.Println(os.Args[3])
-		} else {
-			fmt.Fprintf(os.Stderr, "ERROR: No such annotation: %#v\n", os.Args[2])
-			os.Exit(1)
-		}
+		anns, err := client.PodAnnotations()
+		printAnnotation(anns, err, args[1:])
+	case "annotations":
+		listAnnotations(client, args[1:])
+	case "env":
+		exportEnv(client, args[1:])
 	case "manifest":
-		fmt.Println(mdc.PodManifestJSON())
+		raw, err := client.PodManifestJSON()
+		printManifest(raw, err, func() (interface{}, error) { return client.PodManifest() })
+	case "query":
+		query(client, args[1:])
+	case "apps":
+		listApps(client, args[1:])
+	case "app":
+		showApp(client, args[1:])
 	case "image-id":
-		fmt.Println(mdc.AppImageID())
+		id, err := client.AppImageID()
+		printString("imageID", id, err)
 	case "image-manifest":
-		fmt.Println(mdc.AppImageManifestJSON())
+		raw, err := client.AppImageManifestJSON()
+		printManifest(raw, err, func() (interface{}, error) { return client.AppImageManifest() })
 	case "app-annotation":
-		if len(os.Args) < 3 {
+		if len(args) < 2 {
 			usage(1)
 		}
-		if ann, found := mdc.AppAnnotations().Get(os.Args[2]); found {
-			fmt.Println(ann)
-		} else if len(os.Args) > 3 {
-			fmt.Println(os.Args[3])
-		} else {
-			fmt.Fprintf(os.Stderr, "ERROR: No such annotation: %#v\n", os.Args[2])
-			os.Exit(1)
-		}
-	case "render":
-		if len(os.Args) < 3 {
+		anns, err := client.AppAnnotations()
+		printAnnotation(anns, err, args[1:])
+	case "lookup":
+		lookup(client, args[1:])
+	case "environment":
+		printEnvironment(client, args[1:])
+	case "port":
+		printPorts(client, args[1:])
+	case "mounts":
+		printMounts(client, args[1:])
+	case "sign":
+		if len(args) < 2 {
 			usage(1)
 		}
-		path := os.Args[2]
-		if path == "-" {
-			path = "/dev/stdin"
-		}
-		if err := template.Must(template.ParseFiles(path)).Execute(os.Stdout, mdc); err != nil {
-			panic(err)
-		}
-	case "expand":
-		if len(os.Args) < 3 {
+		sig, err := client.Sign(readArg(args[1]))
+		printString("signature", sig, err)
+	case "verify":
+		if len(args) < 4 {
 			usage(1)
 		}
-		if err := template.Must(template.New("").Parse(os.Args[2])).Execute(os.Stdout, mdc); err != nil {
-			panic(err)
+		ok, err := client.Verify(readArg(args[3]), args[2], args[1])
+		check(err)
+		if structuredOutput() {
+			printDocument(map[string]interface{}{"uuid": args[1], "valid": ok})
+		}
+		if !ok {
+			fatal(errors.New("invalid signature"))
 		}
+	case "wait":
+		wait(client, args[1:])
+	case "render", "expand":
+		renderCommand(client, args)
+	case "render-all":
+		renderAllCommand(client, args[1:])
+	case "exec":
+		execCommand(client, args[1:])
 	default:
 		usage(1)
 	}
@@ -0,0 +1,152 @@
+package mdc
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/appc/spec/schema"
+	"github.com/appc/spec/schema/types"
+)
+
+// App is a view of metadata of a single app in the pod.
+type App struct {
+	Name              string
+	mdc               *MDClient
+	imageID           string
+	annotations       types.Annotations
+	imageManifestJSON []byte
+	imageManifest     *schema.ImageManifest
+}
+
+// App returns view of metadata of the named app in the pod. Metadata
+// is fetched when first needed, and cached along with the client's
+// other metadata.
+func (mdc *MDClient) App(name string) *App {
+	if mdc.apps == nil {
+		mdc.apps = make(map[string]*App)
+	}
+	app := mdc.apps[name]
+	if app == nil {
+		app = &App{Name: name, mdc: mdc}
+		mdc.apps[name] = app
+	}
+	return app
+}
+
+func (app *App) path(rest string) string {
+	return "apps/" + app.Name + "/" + rest
+}
+
+func (app *App) ImageID() (string, error) {
+	if app.imageID == "" {
+		if id, err := app.mdc.getString(app.path("image/id")); err != nil {
+			return "", err
+		} else {
+			app.imageID = id
+		}
+	}
+	return app.imageID, nil
+}
+
+func (app *App) imageManifestBytes() ([]byte, error) {
+	if app.imageManifestJSON == nil {
+		if body, err := app.mdc.Get(app.path("image/manifest")); err != nil {
+			return nil, err
+		} else {
+			app.imageManifestJSON = body
+		}
+	}
+	return app.imageManifestJSON, nil
+}
+
+func (app *App) ImageManifestJSON() (string, error) {
+	body, err := app.imageManifestBytes()
+	return string(body), err
+}
+
+func (app *App) ImageManifest() (*schema.ImageManifest, error) {
+	if app.imageManifest == nil {
+		body, err := app.imageManifestBytes()
+		if err != nil {
+			return nil, err
+		}
+		im := &schema.ImageManifest{}
+		if err := json.Unmarshal(body, im); err != nil {
+			return nil, fmt.Errorf("%s: %v", app.path("image/manifest"), err)
+		}
+		app.imageManifest = im
+	}
+	return app.imageManifest, nil
+}
+
+// RuntimeApp returns the app's entry in the pod manifest, or nil if
+// the pod manifest has no app of that name.
+func (app *App) RuntimeApp() (*schema.RuntimeApp, error) {
+	pm, err := app.mdc.PodManifest()
+	if err != nil {
+		return nil, err
+	}
+	name, err := types.NewACName(app.Name)
+	if err != nil {
+		return nil, nil
+	}
+	return pm.Apps.Get(*name), nil
+}
+
+// runtimeAppOverride returns the app definition overriding the image's
+// one in the pod manifest, or nil
+func (app *App) runtimeAppOverride() (*types.App, error) {
+	ra, err := app.RuntimeApp()
+	if ra == nil || err != nil {
+		return nil, err
+	}
+	return ra.App, nil
+}
+
+// imageApp returns the app definition of the image manifest, or nil if
+// there is none, or the image manifest is not available
+func (app *App) imageApp() (*types.App, error) {
+	im, err := app.ImageManifest()
+	if IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return im.App, nil
+}
+
+func (app *App) Annotations() (types.Annotations, error) {
+	if app.annotations == nil {
+		var anns types.Annotations
+		if err := app.mdc.getJSON(app.path("annotations"), &anns); err != nil {
+			return nil, err
+		}
+		app.annotations = anns
+	}
+	return app.annotations, nil
+}
+
+func (app *App) source() annotationSource {
+	return annotationSource{app.mdc, "app " + app.Name, app.Annotations}
+}
+
+func (app *App) Annotation(name string) (string, error) {
+	return app.source().annotation(name)
+}
+
+func (app *App) HasAnnotation(name string) (bool, error) {
+	return app.source().has(name)
+}
+
+func (app *App) MustAnnotation(name string) (string, error) {
+	return app.source().must(name)
+}
+
+func (app *App) AnnotationOr(name, defaultValue string) (string, error) {
+	return app.source().or(name, defaultValue)
+}
+
+func (app *App) AnnotationsWithPrefix(prefix string) (map[string]string, error) {
+	return app.source().withPrefix(prefix)
+}
@@ -0,0 +1,61 @@
+package mdc
+
+import (
+	"bytes"
+	"testing"
+	"text/template"
+)
+
+func TestApp(t *testing.T) {
+	mdc, err := NewMDClient(Options{Service: newTestPod(t), AppName: "reduce-worker"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if mdc.App("backup") != mdc.App("backup") {
+		t.Error("App view not cached")
+	}
+
+	backup := mdc.App("backup")
+
+	if id, err := backup.ImageID(); err != nil {
+		t.Error("ImageID:", err)
+	} else if id != "sha512-d603c29df0214c9b6681ed591871d40cc4bfabf9914383ce95ada0f2333defa7e97e21ca347e1d8dfde0b3edfe703688729cd25cec895a9a5b5c856da2f031fe" {
+		t.Error("Invalid image ID:", id)
+	}
+
+	if val, err := backup.AnnotationOr("foo", "none"); err != nil {
+		t.Error("AnnotationOr:", err)
+	} else if val != "none" {
+		t.Error("Invalid annotation value:", val)
+	}
+
+	if _, err := backup.ImageManifest(); !IsNotFound(err) {
+		t.Error("Expected not found error, got:", err)
+	}
+
+	if val, err := mdc.App("reduce-worker").MustAnnotation("foo"); err != nil {
+		t.Error("MustAnnotation:", err)
+	} else if val != "baz" {
+		t.Error("Invalid annotation value:", val)
+	}
+
+	if anns, err := mdc.App("reduce-worker").AnnotationsWithPrefix("fo"); err != nil {
+		t.Error("AnnotationsWithPrefix:", err)
+	} else if len(anns) != 1 || anns["o"] != "baz" {
+		t.Error("Invalid annotations:", anns)
+	}
+
+	if _, err := mdc.App("nonexistent").Annotations(); !IsNotFound(err) {
+		t.Error("Expected not found error, got:", err)
+	}
+
+	out := &bytes.Buffer{}
+	tmpl := template.Must(template.New("appc-metadata-client").Parse(
+		`{{with .App "reduce-worker"}}{{.Name}} {{.Annotation "foo"}}{{end}} {{(.App "backup").ImageID}}`))
+	if err := tmpl.Execute(out, mdc); err != nil {
+		t.Error("Error rendering template:", err)
+	} else if actual := out.String(); actual != "reduce-worker baz sha512-d603c29df0214c9b6681ed591871d40cc4bfabf9914383ce95ada0f2333defa7e97e21ca347e1d8dfde0b3edfe703688729cd25cec895a9a5b5c856da2f031fe" {
+		t.Error("Invalid rendered template:", actual)
+	}
+}
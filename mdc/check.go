@@ -0,0 +1,405 @@
+package mdc
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// AnnotationRef is an annotation referenced by a template with
+// a literal name, found by CheckTemplate.
+type AnnotationRef struct {
+	Name string `json:"name"`
+	// Layer is "pod", "app" (current app), "app NAME" (another app,
+	// accessed with .App "NAME"), or "lookup" (layered lookup with
+	// .Annotation and friends).
+	Layer string `json:"layer"`
+	// Method is the accessor, e.g. "MustPodAnnotation".
+	Method string `json:"method"`
+	// Default is true if the template provides a default value, with
+	// an …Or or typed accessor's argument, or by piping to default.
+	Default bool `json:"default"`
+	// Location is TEMPLATE:LINE:COLUMN of the call.
+	Location string `json:"location"`
+}
+
+// CheckTemplate statically analyzes all templates in tmpl's set, as
+// if tmpl was executed with *MDClient as data. It returns annotations
+// referenced with literal names, and errors for calls to methods or
+// fields that don't exist or get a wrong number of arguments.
+//
+// Templates invoked with {{template}} or include are analyzed with the
+// data they are given; templates that are never invoked are analyzed
+// with unknown data, where only annotation accessors are recognized
+// (by name) and nothing is reported as an error. Dynamic annotation
+// names are not reported.
+func CheckTemplate(tmpl *template.Template) ([]AnnotationRef, []error) {
+	c := &checker{
+		set:     tmpl,
+		funcs:   FuncMap(),
+		visited: make(map[string]bool),
+		walked:  make(map[string]bool),
+	}
+
+	c.walkTemplate(tmpl.Name(), staticType{typ: reflect.TypeOf((*MDClient)(nil))})
+
+	var rest []string
+	for _, t := range tmpl.Templates() {
+		rest = append(rest, t.Name())
+	}
+	sort.Strings(rest)
+	for _, name := range rest {
+		if !c.walked[name] {
+			c.walkTemplate(name, staticType{})
+		}
+	}
+
+	return c.refs, c.errs
+}
+
+// staticType is type of a value in a template; nil typ means unknown.
+// For *App values made with a literal app name, app is the name.
+type staticType struct {
+	typ reflect.Type
+	app string
+}
+
+func newStaticType(typ reflect.Type) staticType {
+	if typ == nil || typ.Kind() == reflect.Interface {
+		return staticType{}
+	}
+	return staticType{typ: typ}
+}
+
+// elem returns type of items of a ranged over value
+func (st staticType) elem() (key, elem staticType) {
+	if st.typ != nil {
+		switch st.typ.Kind() {
+		case reflect.Map:
+			return newStaticType(st.typ.Key()), newStaticType(st.typ.Elem())
+		case reflect.Slice, reflect.Array:
+			return newStaticType(reflect.TypeOf(0)), newStaticType(st.typ.Elem())
+		}
+	}
+	return staticType{}, staticType{}
+}
+
+type variable struct {
+	name string
+	st   staticType
+}
+
+type checker struct {
+	set   *template.Template
+	funcs template.FuncMap
+	tree  *parse.Tree
+	vars  []variable
+	refs  []AnnotationRef
+	errs  []error
+	// visited records templates walked with a given data type, to
+	// avoid infinite recursion; walked records template names.
+	visited map[string]bool
+	walked  map[string]bool
+}
+
+func (c *checker) walkTemplate(name string, dot staticType) {
+	t := c.set.Lookup(name)
+	if t == nil || t.Tree == nil || t.Tree.Root == nil {
+		return
+	}
+	key := fmt.Sprintf("%s\x00%v\x00%s", name, dot.typ, dot.app)
+	if c.visited[key] {
+		return
+	}
+	c.visited[key] = true
+	c.walked[name] = true
+
+	tree, vars := c.tree, c.vars
+	c.tree, c.vars = t.Tree, []variable{{"$", dot}}
+	c.walk(t.Tree.Root, dot)
+	c.tree, c.vars = tree, vars
+}
+
+func (c *checker) errorf(node parse.Node, format string, args ...interface{}) {
+	location, _ := c.tree.ErrorContext(node)
+	c.errs = append(c.errs, fmt.Errorf("%s: %s", location, fmt.Sprintf(format, args...)))
+}
+
+func (c *checker) walk(node parse.Node, dot staticType) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n != nil {
+			for _, item := range n.Nodes {
+				c.walk(item, dot)
+			}
+		}
+	case *parse.ActionNode:
+		c.pipe(n.Pipe, dot)
+	case *parse.IfNode:
+		c.branch(&n.BranchNode, dot, false, false)
+	case *parse.WithNode:
+		c.branch(&n.BranchNode, dot, true, false)
+	case *parse.RangeNode:
+		c.branch(&n.BranchNode, dot, false, true)
+	case *parse.TemplateNode:
+		arg := staticType{}
+		if n.Pipe != nil {
+			arg = c.pipe(n.Pipe, dot)
+		}
+		c.walkTemplate(n.Name, arg)
+	}
+}
+
+func (c *checker) branch(n *parse.BranchNode, dot staticType, with, isRange bool) {
+	nvars := len(c.vars)
+	st := c.pipe(n.Pipe, dot)
+	inner := dot
+	switch {
+	case with:
+		inner = st
+	case isRange:
+		key, elem := st.elem()
+		inner = elem
+		switch len(n.Pipe.Decl) {
+		case 1:
+			c.vars[len(c.vars)-1].st = elem
+		case 2:
+			c.vars[len(c.vars)-2].st = key
+			c.vars[len(c.vars)-1].st = elem
+		}
+	}
+	c.walk(n.List, inner)
+	c.walk(n.ElseList, dot)
+	c.vars = c.vars[:nvars]
+}
+
+func (c *checker) pipe(p *parse.PipeNode, dot staticType) staticType {
+	if p == nil {
+		return staticType{}
+	}
+	var st staticType
+	for i, cmd := range p.Cmds {
+		nrefs := len(c.refs)
+		st = c.command(cmd, dot, i > 0)
+		if i+1 < len(p.Cmds) && len(c.refs) > nrefs {
+			if fn, ok := p.Cmds[i+1].Args[0].(*parse.IdentifierNode); ok && fn.Ident == "default" {
+				c.refs[len(c.refs)-1].Default = true
+			}
+		}
+	}
+	for _, v := range p.Decl {
+		if p.IsAssign {
+			for i := len(c.vars) - 1; i >= 0; i-- {
+				if c.vars[i].name == v.Ident[0] {
+					c.vars[i].st = st
+					break
+				}
+			}
+		} else {
+			c.vars = append(c.vars, variable{v.Ident[0], st})
+		}
+	}
+	return st
+}
+
+// command returns type of command's result; piped is true if the
+// previous command's result is passed as the final argument
+func (c *checker) command(cmd *parse.CommandNode, dot staticType, piped bool) staticType {
+	args := cmd.Args[1:]
+	switch n := cmd.Args[0].(type) {
+	case *parse.FieldNode:
+		return c.fields(dot, n.Ident, args, piped, n, dot)
+	case *parse.ChainNode:
+		return c.fields(c.arg(n.Node, dot), n.Field, args, piped, n, dot)
+	case *parse.VariableNode:
+		return c.fields(c.variable(n.Ident[0]), n.Ident[1:], args, piped, n, dot)
+	case *parse.IdentifierNode:
+		return c.function(n, args, dot)
+	}
+	st := c.arg(cmd.Args[0], dot)
+	for _, arg := range args {
+		c.arg(arg, dot)
+	}
+	return st
+}
+
+// arg returns type of a command's argument
+func (c *checker) arg(node parse.Node, dot staticType) staticType {
+	switch n := node.(type) {
+	case *parse.DotNode:
+		return dot
+	case *parse.FieldNode:
+		return c.fields(dot, n.Ident, nil, false, n, dot)
+	case *parse.ChainNode:
+		return c.fields(c.arg(n.Node, dot), n.Field, nil, false, n, dot)
+	case *parse.VariableNode:
+		return c.fields(c.variable(n.Ident[0]), n.Ident[1:], nil, false, n, dot)
+	case *parse.PipeNode:
+		return c.pipe(n, dot)
+	case *parse.IdentifierNode:
+		return c.function(n, nil, dot)
+	case *parse.StringNode:
+		return newStaticType(reflect.TypeOf(""))
+	}
+	return staticType{}
+}
+
+func (c *checker) variable(name string) staticType {
+	for i := len(c.vars) - 1; i >= 0; i-- {
+		if c.vars[i].name == name {
+			return c.vars[i].st
+		}
+	}
+	return staticType{}
+}
+
+func (c *checker) function(n *parse.IdentifierNode, args []parse.Node, dot staticType) staticType {
+	var argTypes []staticType
+	for _, arg := range args {
+		argTypes = append(argTypes, c.arg(arg, dot))
+	}
+	if n.Ident == "include" && len(args) == 2 {
+		if name, ok := args[0].(*parse.StringNode); ok {
+			c.walkTemplate(name.Text, argTypes[1])
+		}
+	}
+	if fn, ok := c.funcs[n.Ident]; ok {
+		if typ := reflect.TypeOf(fn); typ.NumOut() > 0 {
+			return newStaticType(typ.Out(0))
+		}
+	}
+	return staticType{}
+}
+
+// fields resolves a chain of fields or methods on recv; args
+// (evaluated with dot) are given to the last one
+func (c *checker) fields(recv staticType, idents []string, args []parse.Node, piped bool, node parse.Node, dot staticType) staticType {
+	for _, arg := range args {
+		c.arg(arg, dot)
+	}
+	st := recv
+	for i, ident := range idents {
+		if i < len(idents)-1 {
+			st = c.member(st, ident, nil, false, node)
+		} else {
+			st = c.member(st, ident, args, piped, node)
+		}
+	}
+	return st
+}
+
+var (
+	mdClientType = reflect.TypeOf((*MDClient)(nil))
+	appType      = reflect.TypeOf((*App)(nil))
+	errorType    = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+func (c *checker) member(recv staticType, name string, args []parse.Node, piped bool, node parse.Node) staticType {
+	if recv.typ == nil {
+		c.record(recv, name, args, node)
+		return staticType{}
+	}
+
+	if m, ok := recv.typ.MethodByName(name); ok {
+		nargs, nin := len(args), m.Type.NumIn()-1
+		if piped {
+			nargs++
+		}
+		if m.Type.IsVariadic() && nargs < nin-1 || !m.Type.IsVariadic() && nargs != nin {
+			c.errorf(node, "wrong number of arguments for %s: want %d, got %d", name, nin, nargs)
+		}
+		c.record(recv, name, args, node)
+		switch nout := m.Type.NumOut(); {
+		case nout == 0:
+			c.errorf(node, "method %s has no results", name)
+			return staticType{}
+		case nout > 2 || nout == 2 && m.Type.Out(1) != errorType:
+			c.errorf(node, "method %s has too many results", name)
+			return staticType{}
+		}
+		rv := newStaticType(m.Type.Out(0))
+		if recv.typ == mdClientType && name == "App" && len(args) == 1 {
+			if s, ok := args[0].(*parse.StringNode); ok {
+				rv.app = s.Text
+			}
+		}
+		return rv
+	}
+
+	typ := recv.typ
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	switch typ.Kind() {
+	case reflect.Struct:
+		if f, ok := typ.FieldByName(name); ok && f.PkgPath == "" {
+			return newStaticType(f.Type)
+		}
+	case reflect.Map:
+		if typ.Key().Kind() == reflect.String {
+			return newStaticType(typ.Elem())
+		}
+	}
+
+	c.errorf(node, "can't evaluate %s of %v: no such method or field", name, recv.typ)
+	return staticType{}
+}
+
+// typedSuffixes are suffixes of typed annotation accessors
+var typedSuffixes = []string{"Int", "Float", "Bool", "Duration", "Bytes", "List"}
+
+// record adds reference to annotation if method name is an annotation
+// accessor of recv, and its first argument is a literal string
+func (c *checker) record(recv staticType, method string, args []parse.Node, node parse.Node) {
+	if len(args) == 0 {
+		return
+	}
+	name, ok := args[0].(*parse.StringNode)
+	if !ok {
+		return
+	}
+
+	base := strings.TrimPrefix(strings.TrimPrefix(method, "Must"), "Has")
+	hasDefault := false
+	if strings.HasSuffix(base, "Or") {
+		base = strings.TrimSuffix(base, "Or")
+		hasDefault = true
+	} else {
+		for _, suffix := range typedSuffixes {
+			if strings.HasSuffix(base, "Annotation"+suffix) {
+				base = strings.TrimSuffix(base, suffix)
+				hasDefault = len(args) > 1
+				break
+			}
+		}
+	}
+
+	var layer string
+	switch {
+	case base == "PodAnnotation" && recv.typ != appType:
+		layer = LayerPod
+	case base == "AppAnnotation" && recv.typ != appType:
+		layer = LayerApp
+	case base == "Annotation" && recv.typ == appType:
+		layer = "app " + recv.app
+		if recv.app == "" {
+			layer = LayerApp
+		}
+	case base == "Annotation":
+		layer = "lookup"
+	default:
+		return
+	}
+
+	location, _ := c.tree.ErrorContext(node)
+	c.refs = append(c.refs, AnnotationRef{
+		Name:     name.Text,
+		Layer:    layer,
+		Method:   method,
+		Default:  hasDefault,
+		Location: location,
+	})
+}
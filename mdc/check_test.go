@@ -0,0 +1,78 @@
+package mdc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheckTemplate(t *testing.T) {
+	tmpl, err := NewTemplate("main").Parse(`host={{.MustPodAnnotation "postgresql/host"}}
+port={{.PodAnnotationInt "postgresql/port" 5432}}
+user={{.PodAnnotation "postgresql/user" | default "app"}}
+{{with .App "backup"}}{{.Name}} {{.AnnotationOr "schedule" "@daily"}}{{end}}
+{{range $name, $value := .PodAnnotationsWithPrefix "extra/"}}{{$name}}={{$value | upper}}{{end}}
+{{$app := .App "web"}}{{$app.MustAnnotation "port"}} {{.Annotation "region"}}
+{{if .HasAppAnnotation "role"}}{{.AppAnnotation "role"}}{{end}}
+{{template "helper" .}}{{include "unused" 1}}
+{{define "helper"}}{{.MustAppAnnotation "helper"}}{{end}}
+{{define "unused"}}{{.PodAnnotation "unused"}}{{.Whatever}}{{end}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	refs, errs := CheckTemplate(tmpl)
+	if len(errs) > 0 {
+		t.Error("Unexpected errors:", errs)
+	}
+
+	type ref struct {
+		layer, name string
+		def         bool
+	}
+	var got []ref
+	for _, r := range refs {
+		got = append(got, ref{r.Layer, r.Name, r.Default})
+	}
+	expected := []ref{
+		{"pod", "postgresql/host", false},
+		{"pod", "postgresql/port", true},
+		{"pod", "postgresql/user", true},
+		{"app backup", "schedule", true},
+		{"app web", "port", false},
+		{"lookup", "region", false},
+		{"app", "role", false},
+		{"app", "role", false},
+		{"app", "helper", false},
+		{"pod", "unused", false},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Invalid refs:\n%#v\nexpected:\n%#v", got, expected)
+	}
+
+	if refs[0].Method != "MustPodAnnotation" || refs[0].Location != "main:1:7" {
+		t.Errorf("Invalid ref: %#v", refs[0])
+	}
+
+	tmpl, err = NewTemplate("bad").Parse(`{{.PodAnotation "x"}}
+{{.MustPodAnnotation}}
+{{with .App "backup"}}{{.ImageId}}{{end}}
+{{.PodManifest.ACVersion}}{{.PodManifest.NoSuchField}}
+{{.Flush}}
+{{.AppImageManifest.App.Environment.Set "A" "B"}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, errs = CheckTemplate(tmpl)
+	if len(errs) != 6 {
+		t.Fatalf("Expected 6 errors, got: %v", errs)
+	}
+	for i, msg := range []string{"bad:1:", "bad:2:", "bad:3:", "bad:4:", "bad:5:", "bad:6:"} {
+		if !strings.HasPrefix(errs[i].Error(), msg) {
+			t.Errorf("Invalid error %d: %v", i, errs[i])
+		}
+	}
+	if !strings.HasSuffix(errs[4].Error(), "method Flush has no results") {
+		t.Errorf("Invalid error: %v", errs[4])
+	}
+}
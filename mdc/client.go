@@ -0,0 +1,484 @@
+// Package mdc is a client for the App Container Metadata Service.
+//
+// All accessors of MDClient return an error rather than exiting or
+// panicking, so that the client can be embedded in long-running
+// programs; text/template also stops execution when a method returns
+// a non-nil error, so MDClient can be passed directly as template data.
+package mdc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/appc/spec/schema"
+	"github.com/appc/spec/schema/types"
+)
+
+var (
+	ErrNoMetadataURL = errors.New("no metadata service URL (AC_METADATA_URL)")
+	ErrNoAppName     = errors.New("no app name (AC_APP_NAME)")
+)
+
+// StatusError is returned when the metadata service responds with an
+// unexpected HTTP status.
+type StatusError struct {
+	Method, Path string
+	StatusCode   int
+	Status       string
+	Body         []byte
+}
+
+func (err *StatusError) Error() string {
+	return fmt.Sprintf("%s %s: %s", err.Method, err.Path, err.Status)
+}
+
+// IsNotFound returns true if err is a 404 response from the metadata service.
+func IsNotFound(err error) bool {
+	serr, ok := err.(*StatusError)
+	return ok && serr.StatusCode == http.StatusNotFound
+}
+
+// AnnotationNotFoundError is returned by MustPodAnnotation and
+// MustAppAnnotation when the annotation does not exist.
+type AnnotationNotFoundError struct {
+	Name string
+}
+
+func (err *AnnotationNotFoundError) Error() string {
+	return "annotation not found: " + err.Name
+}
+
+// Service answers metadata service requests. Path is relative to the
+// /acMetadata/v1/ root, and form is nil for GET requests. Non-nil
+// error means the request could not be made at all.
+type Service interface {
+	Request(method, path string, form url.Values) (status int, body []byte, err error)
+}
+
+type httpService struct {
+	url    string
+	client *http.Client
+}
+
+func (hs *httpService) Request(method, path string, form url.Values) (int, []byte, error) {
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+
+	req, err := http.NewRequest(method, hs.url+"/acMetadata/v1/"+path, body)
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Add("Metadata-Flavor", "AppContainer")
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	resp, err := hs.client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	return resp.StatusCode, respBody, err
+}
+
+// Options configure a new MDClient.
+type Options struct {
+	// MetadataURL is the base URL of the metadata service.
+	MetadataURL string
+	// Service, if not nil, answers metadata requests instead of the
+	// HTTP metadata service at MetadataURL.
+	Service Service
+	// AppName is the name of the current app within the pod.
+	AppName string
+	// HTTPClient is used to talk to the metadata service;
+	// http.DefaultClient is used if nil.
+	HTTPClient *http.Client
+	// Retry sets timeouts and retries of requests.
+	Retry RetryPolicy
+	// Logf, if not nil, is used to report retried requests.
+	Logf func(format string, v ...interface{})
+	// LookupOrder lists annotation layers searched by Lookup, in order
+	// of precedence; DefaultLookupOrder is used if empty.
+	LookupOrder []string
+}
+
+// OptionsFromEnv returns Options set from the AC_METADATA_URL and
+// AC_APP_NAME environment variables, as set by the App Container
+// Executor, with retry policy from RetryPolicyFromEnv and annotation
+// lookup order from MDC_LOOKUP_ORDER. If MDC_OFFLINE is set, metadata
+// is read from files in that directory (see LoadOffline).
+func OptionsFromEnv() (Options, error) {
+	retry, err := RetryPolicyFromEnv()
+	opts := Options{
+		MetadataURL: os.Getenv("AC_METADATA_URL"),
+		AppName:     os.Getenv("AC_APP_NAME"),
+		Retry:       retry,
+	}
+	if err != nil {
+		return opts, err
+	}
+	if order := os.Getenv("MDC_LOOKUP_ORDER"); order != "" {
+		if opts.LookupOrder, err = ParseLookupOrder(order); err != nil {
+			return opts, fmt.Errorf("MDC_LOOKUP_ORDER: %v", err)
+		}
+	}
+	if dir := os.Getenv("MDC_OFFLINE"); dir != "" {
+		err = opts.LoadOffline(dir)
+	}
+	return opts, err
+}
+
+// MDClient is a client of the metadata service. It caches fetched
+// metadata, and keeps state of Strict and Wait calls, without any
+// locking: an MDClient must not be used by several goroutines at the
+// same time. Concurrent users should create a client each, or
+// serialize access to a shared one.
+type MDClient struct {
+	ACMetadataURL, ACAppName string
+	service                  Service
+	retry                    RetryPolicy
+	started                  time.Time
+	logf                     func(string, ...interface{})
+	uuid                     string
+	podAnnotations           types.Annotations
+	podManifestJSON          []byte
+	podManifest              *schema.PodManifest
+	apps                     map[string]*App
+	lookupOrder              []string
+	strict                   bool
+	missingAnnotations       []string
+}
+
+func NewMDClient(opts Options) (*MDClient, error) {
+	if opts.MetadataURL == "" && opts.Service == nil {
+		return nil, ErrNoMetadataURL
+	}
+
+	if opts.AppName == "" {
+		return nil, ErrNoAppName
+	}
+
+	rv := &MDClient{
+		ACMetadataURL: strings.TrimSuffix(opts.MetadataURL, "/"),
+		ACAppName:     opts.AppName,
+		service:       opts.Service,
+		retry:         opts.Retry,
+		logf:          opts.Logf,
+		lookupOrder:   opts.LookupOrder,
+	}
+
+	if len(rv.lookupOrder) == 0 {
+		rv.lookupOrder = DefaultLookupOrder
+	} else if err := checkLookupOrder(rv.lookupOrder); err != nil {
+		return nil, err
+	}
+
+	if rv.service == nil {
+		httpClient := opts.HTTPClient
+		if httpClient == nil {
+			httpClient = http.DefaultClient
+		}
+		if rv.retry.Timeout > 0 {
+			hc := *httpClient
+			hc.Timeout = rv.retry.Timeout
+			httpClient = &hc
+		}
+		rv.service = &httpService{rv.ACMetadataURL, httpClient}
+	}
+
+	if rv.retry.Backoff <= 0 {
+		rv.retry.Backoff = DefaultRetryPolicy.Backoff
+	}
+
+	if rv.logf == nil {
+		rv.logf = func(string, ...interface{}) {}
+	}
+
+	return rv, nil
+}
+
+// ResetDeadline starts a new retry deadline, counted from the next
+// request, without dropping cached metadata. Programs that keep
+// a client for a long time should call it before each operation (like
+// rendering a template), so that the operation gets its own deadline.
+func (mdc *MDClient) ResetDeadline() {
+	mdc.started = time.Time{}
+}
+
+// do makes a request to the metadata service, retrying it according
+// to the retry policy. A POST request is made if form is not nil.
+func (mdc *MDClient) do(method, path string, form url.Values, okStatus ...int) (int, []byte, error) {
+	if mdc.started.IsZero() {
+		mdc.started = time.Now()
+	}
+	deadline := mdc.started.Add(mdc.retry.Deadline)
+	backoff := mdc.retry.Backoff
+	for attempt := 1; ; attempt++ {
+		status, body, err := mdc.doOnce(method, path, form, okStatus...)
+		if err == nil || !isRetryable(err) {
+			return status, body, err
+		}
+		delay := mdc.retry.delay(backoff)
+		if time.Now().Add(delay).After(deadline) {
+			if attempt > 1 {
+				mdc.logf("%s %s: %v; giving up after %d attempts", method, path, err, attempt)
+			}
+			return status, body, err
+		}
+		mdc.logf("%s %s: %v; retrying in %v (attempt %d)", method, path, err, delay, attempt)
+		time.Sleep(delay)
+		backoff = mdc.retry.next(backoff)
+	}
+}
+
+func (mdc *MDClient) doOnce(method, path string, form url.Values, okStatus ...int) (int, []byte, error) {
+	status, body, err := mdc.service.Request(method, path, form)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	for _, ok := range okStatus {
+		if status == ok {
+			return status, body, nil
+		}
+	}
+
+	return status, nil, &StatusError{
+		Method:     method,
+		Path:       path,
+		StatusCode: status,
+		Status:     fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		Body:       body,
+	}
+}
+
+// Get returns body of a metadata service's resource at path relative
+// to the /acMetadata/v1/ root.
+func (mdc *MDClient) Get(path string) ([]byte, error) {
+	_, body, err := mdc.do("GET", path, nil, http.StatusOK)
+	return body, err
+}
+
+func (mdc *MDClient) getString(path string) (string, error) {
+	body, err := mdc.Get(path)
+	return strings.TrimSpace(string(body)), err
+}
+
+func (mdc *MDClient) getJSON(path string, v interface{}) error {
+	body, err := mdc.Get(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return nil
+}
+
+// Sign returns base64-encoded signature of content, made with the
+// pod's identity.
+func (mdc *MDClient) Sign(content string) (string, error) {
+	_, body, err := mdc.do("POST", "pod/hmac/sign", url.Values{"content": {content}}, http.StatusOK)
+	return strings.TrimSpace(string(body)), err
+}
+
+// Verify returns true if signature of content has been made by pod
+// with given UUID.
+func (mdc *MDClient) Verify(content, signature, podUUID string) (bool, error) {
+	status, _, err := mdc.do("POST", "pod/hmac/verify", url.Values{
+		"content":   {content},
+		"signature": {signature},
+		"uid":       {podUUID},
+	}, http.StatusOK, http.StatusForbidden)
+	return status == http.StatusOK, err
+}
+
+func (mdc *MDClient) UUID() (string, error) {
+	if mdc.uuid == "" {
+		if uuid, err := mdc.getString("pod/uuid"); err != nil {
+			return "", err
+		} else {
+			mdc.uuid = uuid
+		}
+	}
+	return mdc.uuid, nil
+}
+
+func (mdc *MDClient) PodAnnotations() (types.Annotations, error) {
+	if mdc.podAnnotations == nil {
+		var anns types.Annotations
+		if err := mdc.getJSON("pod/annotations", &anns); err != nil {
+			return nil, err
+		}
+		mdc.podAnnotations = anns
+	}
+	return mdc.podAnnotations, nil
+}
+
+func (mdc *MDClient) PodAnnotation(name string) (string, error) {
+	return mdc.podSource().annotation(name)
+}
+
+func (mdc *MDClient) HasPodAnnotation(name string) (bool, error) {
+	return mdc.podSource().has(name)
+}
+
+func (mdc *MDClient) MustPodAnnotation(name string) (string, error) {
+	return mdc.podSource().must(name)
+}
+
+func (mdc *MDClient) PodAnnotationOr(name, defaultValue string) (string, error) {
+	return mdc.podSource().or(name, defaultValue)
+}
+
+// PodAnnotationsWithPrefix returns pod annotations with names starting
+// with prefix, by name with the prefix stripped.
+func (mdc *MDClient) PodAnnotationsWithPrefix(prefix string) (map[string]string, error) {
+	return mdc.podSource().withPrefix(prefix)
+}
+
+func (mdc *MDClient) podManifestBytes() ([]byte, error) {
+	if mdc.podManifestJSON == nil {
+		if body, err := mdc.Get("pod/manifest"); err != nil {
+			return nil, err
+		} else {
+			mdc.podManifestJSON = body
+		}
+	}
+	return mdc.podManifestJSON, nil
+}
+
+func (mdc *MDClient) PodManifestJSON() (string, error) {
+	body, err := mdc.podManifestBytes()
+	return string(body), err
+}
+
+func (mdc *MDClient) PodManifest() (*schema.PodManifest, error) {
+	if mdc.podManifest == nil {
+		body, err := mdc.podManifestBytes()
+		if err != nil {
+			return nil, err
+		}
+		pm := &schema.PodManifest{}
+		if err := json.Unmarshal(body, pm); err != nil {
+			return nil, fmt.Errorf("pod/manifest: %v", err)
+		}
+		mdc.podManifest = pm
+	}
+	return mdc.podManifest, nil
+}
+
+func (mdc *MDClient) AppImageID() (string, error) {
+	return mdc.App(mdc.ACAppName).ImageID()
+}
+
+func (mdc *MDClient) AppImageManifestJSON() (string, error) {
+	return mdc.App(mdc.ACAppName).ImageManifestJSON()
+}
+
+func (mdc *MDClient) AppImageManifest() (*schema.ImageManifest, error) {
+	return mdc.App(mdc.ACAppName).ImageManifest()
+}
+
+func (mdc *MDClient) AppAnnotations() (types.Annotations, error) {
+	return mdc.App(mdc.ACAppName).Annotations()
+}
+
+func (mdc *MDClient) AppAnnotation(name string) (string, error) {
+	return mdc.App(mdc.ACAppName).Annotation(name)
+}
+
+func (mdc *MDClient) HasAppAnnotation(name string) (bool, error) {
+	return mdc.App(mdc.ACAppName).HasAnnotation(name)
+}
+
+func (mdc *MDClient) MustAppAnnotation(name string) (string, error) {
+	return mdc.App(mdc.ACAppName).MustAnnotation(name)
+}
+
+func (mdc *MDClient) AppAnnotationOr(name, defaultValue string) (string, error) {
+	return mdc.App(mdc.ACAppName).AnnotationOr(name, defaultValue)
+}
+
+// AppAnnotationsWithPrefix returns current app's annotations with
+// names starting with prefix, by name with the prefix stripped.
+func (mdc *MDClient) AppAnnotationsWithPrefix(prefix string) (map[string]string, error) {
+	return mdc.App(mdc.ACAppName).AnnotationsWithPrefix(prefix)
+}
+
+// annotationSource is a set of annotations that accessors operate on
+type annotationSource struct {
+	mdc *MDClient
+	// desc names the source in strict mode's report, e.g. "pod"
+	desc string
+	get  func() (types.Annotations, error)
+}
+
+func (mdc *MDClient) podSource() annotationSource {
+	return annotationSource{mdc, "pod", mdc.PodAnnotations}
+}
+
+// value returns annotation's value, and whether it has been found
+func (src annotationSource) value(name string) (string, bool, error) {
+	anns, err := src.get()
+	if err != nil {
+		return "", false, err
+	}
+	v, found := anns.Get(name)
+	return v, found, nil
+}
+
+func (src annotationSource) annotation(name string) (string, error) {
+	v, found, err := src.value(name)
+	if err == nil && !found {
+		src.mdc.missing(src.desc, name)
+	}
+	return v, err
+}
+
+func (src annotationSource) has(name string) (bool, error) {
+	_, found, err := src.value(name)
+	return found, err
+}
+
+func (src annotationSource) must(name string) (string, error) {
+	v, found, err := src.value(name)
+	if err == nil && !found {
+		err = src.mdc.notFound(src.desc, name)
+	}
+	return v, err
+}
+
+func (src annotationSource) or(name, defaultValue string) (string, error) {
+	v, found, err := src.value(name)
+	if err == nil && !found {
+		v = defaultValue
+	}
+	return v, err
+}
+
+func (src annotationSource) withPrefix(prefix string) (map[string]string, error) {
+	anns, err := src.get()
+	if err != nil {
+		return nil, err
+	}
+	rv := make(map[string]string)
+	for _, ann := range anns {
+		if name := ann.Name.String(); strings.HasPrefix(name, prefix) {
+			rv[strings.TrimPrefix(name, prefix)] = ann.Value
+		}
+	}
+	return rv, nil
+}
@@ -0,0 +1,347 @@
+package mdc
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"text/template"
+)
+
+var pod_uuid = "26E56A04-F590-11E4-A66F-D7B3DD9DA696"
+var pod_manifest = `{
+    "acVersion": "0.5.1",
+    "acKind": "PodManifest",
+    "apps": [
+        {
+            "name": "reduce-worker",
+            "image": {
+                "name": "example.com/reduce-worker",
+                "id": "sha512-8d3fffddf79e9a232ffd19f9ccaa4d6b37a6a243dbe0f23137b108a043d9da13121a9b505c804956b22e93c7f93969f4a7ba8ddea45bf4aab0bebc8f814e0990"
+            },
+            "annotations": [{"name": "foo", "value": "baz"}]
+        },
+        {
+            "name": "backup",
+            "image": {
+                "name": "example.com/worker-backup",
+                "id": "sha512-d603c29df0214c9b6681ed591871d40cc4bfabf9914383ce95ada0f2333defa7e97e21ca347e1d8dfde0b3edfe703688729cd25cec895a9a5b5c856da2f031fe",
+                "labels": [{"name": "version", "value": "latest"}]
+            }
+        }
+    ],
+    "annotations": [
+        {"name": "ip-address", "value": "10.1.2.3"},
+        {"name": "postgresql/host", "value": "db.example.com"},
+        {"name": "postgresql/port", "value": "5432"}
+    ]
+}`
+var image_manifest = `{
+    "acKind": "ImageManifest",
+    "acVersion": "0.5.1",
+    "name": "example.com/reduce-worker",
+    "labels": [
+        {"name": "version", "value": "1.0.0"},
+        {"name": "arch", "value": "amd64"},
+        {"name": "os", "value": "linux"}
+    ],
+    "app": {
+        "exec": ["/usr/bin/reduce-worker", "--quiet"],
+        "user": "100",
+        "group": "300",
+        "eventHandlers": [
+            {"name": "pre-start", "exec": ["/usr/bin/data-downloader"]},
+            {"name": "post-stop", "exec": ["/usr/bin/deregister-worker", "--verbose"]}
+        ],
+        "environment": [
+            {"name": "REDUCE_WORKER_DEBUG", "value": "true"}
+        ],
+        "isolators": [
+            {"name": "resource/cpu", "value": {"limit": "20"}},
+            {"name": "resource/memory", "value": {"limit": "1G"}},
+            {"name": "os/linux/capabilities-revoke-set", "value": {"set": ["CAP_NET_BIND_SERVICE", "CAP_SYS_ADMIN"]}}
+        ],
+        "ports": [{"name": "health", "port": 4000, "protocol": "tcp", "socketActivated": true}]
+    },
+    "dependencies": [
+        {
+            "imageName": "example.com/reduce-worker-base",
+            "imageID": "sha512-7fa909434c9683e9db38a56a35f83e838a2df25b9c6c13dd3d9ce25ec6463b3cac338c94289528cf5f8b9e70e9bcdf59246fe05e7b91489ee5fb9cb0c7db92cd",
+            "labels": [
+                {"name": "os", "value": "linux"},
+                {"name": "env", "value": "canary"}
+            ]
+        }
+    ],
+    "pathWhitelist": ["/etc/ca/example.com/crt", "/usr/bin/map-reduce-worker", "/opt/libs/reduce-toolkit.so", "/etc/reduce-worker.conf", "/etc/systemd/system/"],
+    "annotations": [
+        {"name": "authors", "value": "Carly Container <carly@example.com>, Nat Network <[nat@example.com](mailto:nat@example.com)>"},
+        {"name": "created", "value": "2014-10-27T19:32:27.67021798Z"},
+        {"name": "documentation", "value": "https://example.com/docs"},
+        {"name": "homepage", "value": "https://example.com"}
+    ]
+}`
+
+var hmacKey = []byte("not a very secret key")
+
+func podHMAC(content string) string {
+	mac := hmac.New(sha512.New, hmacKey)
+	mac.Write([]byte(content))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func serveMetadata(w http.ResponseWriter, r *http.Request) {
+	if hdr, ok := r.Header["Metadata-Flavor"]; !ok || len(hdr) != 1 || hdr[0] != "AppContainer" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Metadata-Flavor header missing or invalid"))
+		return
+	}
+
+	switch r.URL.Path {
+	case "/acMetadata/v1/pod/hmac/sign":
+		if r.Method != "POST" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Write([]byte(podHMAC(r.FormValue("content"))))
+	case "/acMetadata/v1/pod/hmac/verify":
+		if r.Method != "POST" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if r.FormValue("uid") != pod_uuid || !hmac.Equal([]byte(r.FormValue("signature")), []byte(podHMAC(r.FormValue("content")))) {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	case "/acMetadata/v1/pod/uuid":
+		w.Write([]byte(pod_uuid))
+	case "/acMetadata/v1/pod/manifest":
+		w.Write([]byte(pod_manifest))
+	case "/acMetadata/v1/pod/annotations":
+		w.Write([]byte(`[
+        {"name": "ip-address", "value": "10.1.2.3"},
+        {"name": "postgresql/host", "value": "db.example.com"},
+        {"name": "postgresql/port", "value": "5432"}]`))
+	case "/acMetadata/v1/apps/reduce-worker/image/id":
+		w.Write([]byte("sha512-8d3fffddf79e9a232ffd19f9ccaa4d6b37a6a243dbe0f23137b108a043d9da13121a9b505c804956b22e93c7f93969f4a7ba8ddea45bf4aab0bebc8f814e0990"))
+	case "/acMetadata/v1/apps/reduce-worker/image/manifest":
+		w.Write([]byte(image_manifest))
+	case "/acMetadata/v1/apps/reduce-worker/annotations":
+		w.Write([]byte(`[
+        {"name": "foo", "value": "baz"},
+        {"name": "authors", "value": "Carly Container <carly@example.com>, Nat Network <[nat@example.com](mailto:nat@example.com)>"},
+        {"name": "created", "value": "2014-10-27T19:32:27.67021798Z"},
+        {"name": "documentation", "value": "https://example.com/docs"},
+        {"name": "homepage", "value": "https://example.com"}]`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+var mds = httptest.NewServer(http.HandlerFunc(serveMetadata))
+
+func newTestClient(t *testing.T) *MDClient {
+	mdc, err := NewMDClient(Options{MetadataURL: mds.URL, AppName: "reduce-worker"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mdc
+}
+
+func TestNewMDClient(t *testing.T) {
+	if _, err := NewMDClient(Options{AppName: "reduce-worker"}); err != ErrNoMetadataURL {
+		t.Error("Expected ErrNoMetadataURL, got:", err)
+	}
+
+	if _, err := NewMDClient(Options{MetadataURL: mds.URL}); err != ErrNoAppName {
+		t.Error("Expected ErrNoAppName, got:", err)
+	}
+}
+
+func TestMDCApi(t *testing.T) {
+	mdc := newTestClient(t)
+
+	if mdc.ACAppName != "reduce-worker" {
+		t.Error("Invalid ACAppName", mdc.ACAppName)
+	}
+
+	if uuid, err := mdc.UUID(); err != nil {
+		t.Error("UUID:", err)
+	} else if uuid != pod_uuid {
+		t.Error("Invalid UUID:", uuid)
+	}
+
+	if pm, err := mdc.PodManifestJSON(); err != nil {
+		t.Error("PodManifestJSON:", err)
+	} else if pm != pod_manifest {
+		t.Error("Invalid pod manifest")
+	}
+
+	podAnnotations, err := mdc.PodAnnotations()
+	if err != nil {
+		t.Fatal("PodAnnotations:", err)
+	}
+
+	val, found := podAnnotations.Get("ip-address")
+	if !found {
+		t.Error("ip-address pod annotation not found")
+	}
+	if val != "10.1.2.3" {
+		t.Error("Invalid annotation value:", val)
+	}
+
+	_, found = podAnnotations.Get("whatever")
+	if found {
+		t.Error("whatever pod annotation found")
+	}
+
+	if id, err := mdc.AppImageID(); err != nil {
+		t.Error("AppImageID:", err)
+	} else if id != "sha512-8d3fffddf79e9a232ffd19f9ccaa4d6b37a6a243dbe0f23137b108a043d9da13121a9b505c804956b22e93c7f93969f4a7ba8ddea45bf4aab0bebc8f814e0990" {
+		t.Error("Invalid app image ID:", id)
+	}
+
+	if im, err := mdc.AppImageManifestJSON(); err != nil {
+		t.Error("AppImageManifestJSON:", err)
+	} else if im != image_manifest {
+		t.Error("Invalid pod manifest")
+	}
+
+	appAnnotations, err := mdc.AppAnnotations()
+	if err != nil {
+		t.Fatal("AppAnnotations:", err)
+	}
+
+	val, found = appAnnotations.Get("foo")
+	if !found {
+		t.Error("App annotation foo not found")
+	}
+	if val != "baz" {
+		t.Error("Invalid annotation value:", val)
+	}
+
+	_, found = appAnnotations.Get("bar")
+	if found {
+		t.Error("Nonexistent app annotation bar found")
+	}
+}
+
+func TestAnnotationsWithPrefix(t *testing.T) {
+	mdc := newTestClient(t)
+
+	if anns, err := mdc.PodAnnotationsWithPrefix("postgresql/"); err != nil {
+		t.Error("PodAnnotationsWithPrefix:", err)
+	} else if !reflect.DeepEqual(anns, map[string]string{"host": "db.example.com", "port": "5432"}) {
+		t.Error("Invalid annotations:", anns)
+	}
+
+	if anns, err := mdc.PodAnnotationsWithPrefix("mysql/"); err != nil {
+		t.Error("PodAnnotationsWithPrefix:", err)
+	} else if len(anns) != 0 {
+		t.Error("Invalid annotations:", anns)
+	}
+
+	if anns, err := mdc.AppAnnotationsWithPrefix("foo"); err != nil {
+		t.Error("AppAnnotationsWithPrefix:", err)
+	} else if !reflect.DeepEqual(anns, map[string]string{"": "baz"}) {
+		t.Error("Invalid annotations:", anns)
+	}
+
+	out := &bytes.Buffer{}
+	tmpl := template.Must(template.New("appc-metadata-client").Parse(
+		`{{range $k, $v := .PodAnnotationsWithPrefix "postgresql/"}}{{$k}}={{$v}};{{end}}`))
+	if err := tmpl.Execute(out, mdc); err != nil {
+		t.Error("Error rendering template:", err)
+	} else if actual := out.String(); actual != "host=db.example.com;port=5432;" {
+		t.Error("Invalid rendered template:", actual)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	mdc := newTestClient(t)
+
+	if _, err := mdc.MustPodAnnotation("whatever"); err == nil {
+		t.Error("MustPodAnnotation of nonexistent annotation didn't fail")
+	} else if aerr, ok := err.(*AnnotationNotFoundError); !ok || aerr.Name != "whatever" {
+		t.Error("Unexpected error:", err)
+	}
+
+	if _, err := mdc.Get("pod/nonexistent"); !IsNotFound(err) {
+		t.Error("Expected not found error, got:", err)
+	}
+
+	mdc.ACAppName = "nonexistent"
+	if _, err := mdc.AppImageManifest(); !IsNotFound(err) {
+		t.Error("Expected not found error, got:", err)
+	}
+
+	if _, err := mdc.AppAnnotation("foo"); !IsNotFound(err) {
+		t.Error("Expected not found error, got:", err)
+	}
+}
+
+func TestIdentity(t *testing.T) {
+	mdc := newTestClient(t)
+
+	sig, err := mdc.Sign("some content")
+	if err != nil {
+		t.Fatal("Sign:", err)
+	}
+	if sig != podHMAC("some content") {
+		t.Error("Invalid signature:", sig)
+	}
+
+	if ok, err := mdc.Verify("some content", sig, pod_uuid); err != nil {
+		t.Error("Verify:", err)
+	} else if !ok {
+		t.Error("Valid signature not verified")
+	}
+
+	if ok, err := mdc.Verify("other content", sig, pod_uuid); err != nil {
+		t.Error("Verify:", err)
+	} else if ok {
+		t.Error("Signature of other content verified")
+	}
+
+	if ok, err := mdc.Verify("some content", sig, "54F4E25C-F5A3-11E4-A3F1-D7B3DD9DA696"); err != nil {
+		t.Error("Verify:", err)
+	} else if ok {
+		t.Error("Signature verified for other pod UUID")
+	}
+}
+
+const templateText = `
+I am a {{.ACAppName}}, {{.UUID}}, running {{.AppImageID}}
+My IP address is {{.PodAnnotation "ip-address"}} {{.PodAnnotationOr "ip-address" "0.0.0.0"}}
+My whatever is "{{.PodAnnotation "whatever"}}" "{{.PodAnnotationOr "whatever" "whatevs"}}"
+My "foo" is "{{.AppAnnotation "foo"}}" "{{.AppAnnotationOr "foo" "quux"}}"
+My "bar" is "{{.AppAnnotation "bar"}}" "{{.AppAnnotationOr "bar" "quux"}}"
+`
+
+const templateExpected = `
+I am a reduce-worker, 26E56A04-F590-11E4-A66F-D7B3DD9DA696, running sha512-8d3fffddf79e9a232ffd19f9ccaa4d6b37a6a243dbe0f23137b108a043d9da13121a9b505c804956b22e93c7f93969f4a7ba8ddea45bf4aab0bebc8f814e0990
+My IP address is 10.1.2.3 10.1.2.3
+My whatever is "" "whatevs"
+My "foo" is "baz" "baz"
+My "bar" is "" "quux"
+`
+
+func TestTemplateRendering(t *testing.T) {
+	mdc := newTestClient(t)
+	out := &bytes.Buffer{}
+
+	tmpl := template.Must(template.New("appc-metadata-client").Parse(templateText))
+	if err := tmpl.Execute(out, mdc); err != nil {
+		t.Error("Error rendering template:", err)
+	} else if actual := out.String(); actual != templateExpected {
+		t.Errorf("Rendered template: got %#v, but expected %#v", actual, templateExpected)
+	}
+
+	tmpl = template.Must(template.New("appc-metadata-client").Parse(`{{.MustAppAnnotation "bar"}}`))
+	if err := tmpl.Execute(out, mdc); err == nil {
+		t.Error("Rendering nonexistent MustAppAnnotation didn't fail")
+	}
+}
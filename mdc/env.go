@@ -0,0 +1,90 @@
+package mdc
+
+import (
+	"os"
+
+	"github.com/appc/spec/schema/types"
+)
+
+// ManifestEnvironment returns the app's environment defined by
+// manifests: variables set in the image manifest's app, overridden
+// and extended by the pod manifest's app.
+func (app *App) ManifestEnvironment() (types.Environment, error) {
+	env := types.Environment{}
+
+	ia, err := app.imageApp()
+	if err != nil {
+		return nil, err
+	}
+	if ia != nil {
+		env = append(env, ia.Environment...)
+	}
+
+	ra, err := app.runtimeAppOverride()
+	if err != nil {
+		return nil, err
+	}
+	if ra != nil {
+		for _, ev := range ra.Environment {
+			env.Set(ev.Name, ev.Value)
+		}
+	}
+
+	return env, nil
+}
+
+// ManifestEnv returns value of the app's environment variable defined
+// by manifests (see ManifestEnvironment), or empty string if it is not
+// defined.
+func (app *App) ManifestEnv(name string) (string, error) {
+	env, err := app.ManifestEnvironment()
+	if err != nil {
+		return "", err
+	}
+	value, _ := env.Get(name)
+	return value, nil
+}
+
+// Environment returns the current app's effective environment: the
+// variables defined by manifests (see App.ManifestEnvironment), with
+// values from the process environment if they are set there.
+func (mdc *MDClient) Environment() (types.Environment, error) {
+	env, err := mdc.App(mdc.ACAppName).ManifestEnvironment()
+	if err != nil {
+		return nil, err
+	}
+	for i, ev := range env {
+		if value, ok := os.LookupEnv(ev.Name); ok {
+			env[i].Value = value
+		}
+	}
+	return env, nil
+}
+
+// Env returns value of an environment variable of the current app: from
+// the process environment, or the pod manifest's app, or the image
+// manifest, whichever defines it first. Empty string is returned if
+// none of them does.
+func (mdc *MDClient) Env(name string) (string, error) {
+	return mdc.EnvOr(name, "")
+}
+
+func (mdc *MDClient) EnvOr(name, defaultValue string) (string, error) {
+	if value, ok := os.LookupEnv(name); ok {
+		return value, nil
+	}
+	env, err := mdc.App(mdc.ACAppName).ManifestEnvironment()
+	if err != nil {
+		return "", err
+	}
+	if value, ok := env.Get(name); ok {
+		return value, nil
+	}
+	return defaultValue, nil
+}
+
+// ManifestEnv returns value of current app's environment variable
+// defined by manifests, ignoring the process environment.
+func (mdc *MDClient) ManifestEnv(name string) (string, error) {
+	return mdc.App(mdc.ACAppName).ManifestEnv(name)
+}
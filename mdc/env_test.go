@@ -0,0 +1,95 @@
+package mdc
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/appc/spec/schema/types"
+)
+
+func TestEnvironment(t *testing.T) {
+	pod := newTestPod(t)
+	pod.Manifest.Apps[0].App = &types.App{}
+	if err := json.Unmarshal([]byte(`{
+        "exec": ["/usr/bin/reduce-worker"],
+        "user": "100",
+        "group": "300",
+        "environment": [
+            {"name": "REDUCE_WORKER_DEBUG", "value": "false"},
+            {"name": "MDC_TEST_WORKERS", "value": "4"}
+        ]
+    }`), pod.Manifest.Apps[0].App); err != nil {
+		t.Fatal(err)
+	}
+
+	mdc, err := NewMDClient(Options{Service: pod, AppName: "reduce-worker"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv("MDC_TEST_WORKERS", "8")
+	defer os.Unsetenv("MDC_TEST_WORKERS")
+
+	if env, err := mdc.App("reduce-worker").ManifestEnvironment(); err != nil {
+		t.Error("ManifestEnvironment:", err)
+	} else if expected := (types.Environment{
+		{Name: "REDUCE_WORKER_DEBUG", Value: "false"},
+		{Name: "MDC_TEST_WORKERS", Value: "4"},
+	}); !reflect.DeepEqual(env, expected) {
+		t.Errorf("Invalid manifest environment: %#v", env)
+	}
+
+	if env, err := mdc.Environment(); err != nil {
+		t.Error("Environment:", err)
+	} else if expected := (types.Environment{
+		{Name: "REDUCE_WORKER_DEBUG", Value: "false"},
+		{Name: "MDC_TEST_WORKERS", Value: "8"},
+	}); !reflect.DeepEqual(env, expected) {
+		t.Errorf("Invalid environment: %#v", env)
+	}
+
+	for name, expected := range map[string]string{
+		"REDUCE_WORKER_DEBUG":  "false",
+		"MDC_TEST_WORKERS":     "8",
+		"MDC_TEST_NONEXISTENT": "",
+	} {
+		if value, err := mdc.Env(name); err != nil {
+			t.Error("Env:", err)
+		} else if value != expected {
+			t.Errorf("Env(%q) = %q, expected %q", name, value, expected)
+		}
+	}
+
+	if value, err := mdc.ManifestEnv("MDC_TEST_WORKERS"); err != nil {
+		t.Error("ManifestEnv:", err)
+	} else if value != "4" {
+		t.Error("Invalid manifest value:", value)
+	}
+
+	if value, err := mdc.EnvOr("MDC_TEST_NONEXISTENT", "dflt"); err != nil {
+		t.Error("EnvOr:", err)
+	} else if value != "dflt" {
+		t.Error("Invalid default:", value)
+	}
+
+	// Image manifest's environment, without runtime override
+	pod.Manifest.Apps[0].App = nil
+	mdc, err = NewMDClient(Options{Service: pod, AppName: "reduce-worker"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, err := mdc.Env("REDUCE_WORKER_DEBUG"); err != nil {
+		t.Error("Env:", err)
+	} else if value != "true" {
+		t.Error("Invalid image manifest value:", value)
+	}
+
+	// No image manifest and no override
+	if env, err := mdc.App("backup").ManifestEnvironment(); err != nil {
+		t.Error("ManifestEnvironment:", err)
+	} else if len(env) != 0 {
+		t.Errorf("Expected empty environment, got: %#v", env)
+	}
+}
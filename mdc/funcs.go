@@ -0,0 +1,522 @@
+package mdc
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// FuncMap returns functions available in templates rendered by mdc, in
+// addition to text/template's builtins. Arguments are ordered so that
+// the value being operated on comes last and can be piped, e.g.
+// {{.PodAnnotation "name" | default "none" | upper}}.
+//
+// Strings:
+//
+//	default DEFAULT VALUE  -- DEFAULT if VALUE is empty (or zero), else VALUE
+//	upper S, lower S, title S
+//	trim S, trimPrefix PREFIX S, trimSuffix SUFFIX S
+//	replace OLD NEW S      -- replace all occurrences of OLD with NEW
+//	contains SUBSTR S, hasPrefix PREFIX S, hasSuffix SUFFIX S
+//	repeat N S
+//	split SEP S            -- list of substrings separated by SEP
+//	join SEP LIST
+//	quote S, squote S      -- S in double (Go syntax) or single quotes
+//	indent N S             -- indent each line of S by N spaces
+//	nindent N S            -- newline followed by indent N S
+//
+// Lists and maps:
+//
+//	list ITEM...           -- list of items
+//	first LIST, last LIST, rest LIST
+//	append LIST ITEM...    -- new list with items appended
+//	has ITEM LIST          -- true if LIST contains ITEM
+//	uniq LIST              -- LIST without duplicate items
+//	sortAlpha LIST         -- LIST's items as sorted strings
+//	dict KEY VALUE...      -- map of string keys to values
+//	keys MAP               -- sorted keys of map
+//	hasKey MAP KEY, get MAP KEY
+//
+// Encoding:
+//
+//	toJson V, toPrettyJson V, fromJson S
+//	toYaml V, fromYaml S
+//	b64enc S, b64dec S
+//
+// Numbers (arguments may be numbers or numeric strings):
+//
+//	toInt V, toFloat V
+//	add A B, sub A B, mul A B, div A B, mod A B, max A B..., min A B...
+//	addf A B, subf A B, mulf A B, divf A B  -- floating point variants
+//
+// Time:
+//
+//	now                    -- current time
+//	date LAYOUT T          -- format T (time.Time or RFC 3339 string) with Go's LAYOUT
+//	parseTime LAYOUT S     -- parse S with Go's LAYOUT
+//	unixTime T             -- seconds since Unix epoch
+//	duration S             -- parse Go duration string (e.g. "1h30m")
+//
+// Templates:
+//
+//	include NAME DATA      -- output of template NAME executed with DATA,
+//	                          as a string (only in templates made with
+//	                          NewTemplate)
+func FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"default":    defaultValue,
+		"upper":      strings.ToUpper,
+		"lower":      strings.ToLower,
+		"title":      strings.Title,
+		"trim":       strings.TrimSpace,
+		"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+		"replace":    func(old, new, s string) string { return strings.Replace(s, old, new, -1) },
+		"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+		"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+		"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+		"repeat":     func(n int, s string) string { return strings.Repeat(s, n) },
+		"split":      func(sep, s string) []string { return strings.Split(s, sep) },
+		"join":       join,
+		"quote":      strconv.Quote,
+		"squote":     func(s string) string { return "'" + strings.Replace(s, "'", `'\''`, -1) + "'" },
+		"indent":     indent,
+		"nindent":    func(n int, s string) string { return "\n" + indent(n, s) },
+
+		"list":      func(items ...interface{}) []interface{} { return items },
+		"first":     first,
+		"last":      last,
+		"rest":      rest,
+		"append":    appendList,
+		"has":       has,
+		"uniq":      uniq,
+		"sortAlpha": sortAlpha,
+		"dict":      dict,
+		"keys":      keys,
+		"hasKey":    hasKey,
+		"get":       get,
+
+		"toJson":       toJSON,
+		"toPrettyJson": toPrettyJSON,
+		"fromJson":     fromJSON,
+		"toYaml":       toYAML,
+		"fromYaml":     fromYAML,
+		"b64enc":       func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) },
+		"b64dec":       b64dec,
+
+		"toInt":   toInt,
+		"toFloat": toFloat,
+		"add":     intOp(func(a, b int64) int64 { return a + b }, false),
+		"sub":     intOp(func(a, b int64) int64 { return a - b }, false),
+		"mul":     intOp(func(a, b int64) int64 { return a * b }, false),
+		"div":     intOp(func(a, b int64) int64 { return a / b }, true),
+		"mod":     intOp(func(a, b int64) int64 { return a % b }, true),
+		"max":     minMax(func(a, b int64) bool { return a > b }),
+		"min":     minMax(func(a, b int64) bool { return a < b }),
+		"addf":    floatOp(func(a, b float64) float64 { return a + b }),
+		"subf":    floatOp(func(a, b float64) float64 { return a - b }),
+		"mulf":    floatOp(func(a, b float64) float64 { return a * b }),
+		"divf":    floatOp(func(a, b float64) float64 { return a / b }),
+
+		"now":       time.Now,
+		"date":      date,
+		"parseTime": time.Parse,
+		"unixTime":  unixTime,
+		"duration":  time.ParseDuration,
+
+		"include": func(string, interface{}) (string, error) {
+			return "", errors.New("include is only available in templates made with NewTemplate")
+		},
+	}
+}
+
+// isEmpty returns true for nil and zero values, and empty slices and maps
+func isEmpty(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return rv.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return rv.IsNil()
+	}
+	return reflect.DeepEqual(v, reflect.Zero(rv.Type()).Interface())
+}
+
+func defaultValue(def, v interface{}) interface{} {
+	if isEmpty(v) {
+		return def
+	}
+	return v
+}
+
+// toList converts any slice or array to []interface{}
+func toList(v interface{}) ([]interface{}, error) {
+	if l, ok := v.([]interface{}); ok {
+		return l, nil
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Array, reflect.Slice:
+		l := make([]interface{}, rv.Len())
+		for i := range l {
+			l[i] = rv.Index(i).Interface()
+		}
+		return l, nil
+	}
+	return nil, fmt.Errorf("not a list: %#v", v)
+}
+
+func join(sep string, v interface{}) (string, error) {
+	l, err := toList(v)
+	if err != nil {
+		return "", err
+	}
+	strs := make([]string, len(l))
+	for i, item := range l {
+		strs[i] = fmt.Sprint(item)
+	}
+	return strings.Join(strs, sep), nil
+}
+
+func indent(n int, s string) string {
+	pad := strings.Repeat(" ", n)
+	return pad + strings.Replace(s, "\n", "\n"+pad, -1)
+}
+
+func first(v interface{}) (interface{}, error) {
+	l, err := toList(v)
+	if err != nil || len(l) == 0 {
+		return nil, err
+	}
+	return l[0], nil
+}
+
+func last(v interface{}) (interface{}, error) {
+	l, err := toList(v)
+	if err != nil || len(l) == 0 {
+		return nil, err
+	}
+	return l[len(l)-1], nil
+}
+
+func rest(v interface{}) ([]interface{}, error) {
+	l, err := toList(v)
+	if err != nil || len(l) == 0 {
+		return nil, err
+	}
+	return l[1:], nil
+}
+
+func appendList(v interface{}, items ...interface{}) ([]interface{}, error) {
+	l, err := toList(v)
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]interface{}{}, l...), items...), nil
+}
+
+func has(item, v interface{}) (bool, error) {
+	l, err := toList(v)
+	if err != nil {
+		return false, err
+	}
+	for _, li := range l {
+		if reflect.DeepEqual(li, item) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func uniq(v interface{}) ([]interface{}, error) {
+	l, err := toList(v)
+	if err != nil {
+		return nil, err
+	}
+	var rv []interface{}
+	for _, item := range l {
+		if found, _ := has(item, rv); !found {
+			rv = append(rv, item)
+		}
+	}
+	return rv, nil
+}
+
+func sortAlpha(v interface{}) ([]string, error) {
+	l, err := toList(v)
+	if err != nil {
+		return nil, err
+	}
+	strs := make([]string, len(l))
+	for i, item := range l {
+		strs[i] = fmt.Sprint(item)
+	}
+	sort.Strings(strs)
+	return strs, nil
+}
+
+func dict(kv ...interface{}) (map[string]interface{}, error) {
+	if len(kv)%2 != 0 {
+		return nil, errors.New("dict: odd number of arguments")
+	}
+	rv := make(map[string]interface{}, len(kv)/2)
+	for i := 0; i < len(kv); i += 2 {
+		rv[fmt.Sprint(kv[i])] = kv[i+1]
+	}
+	return rv, nil
+}
+
+func mapValue(m interface{}) (reflect.Value, error) {
+	rv := reflect.ValueOf(m)
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+		return rv, fmt.Errorf("not a map with string keys: %#v", m)
+	}
+	return rv, nil
+}
+
+func keys(m interface{}) ([]string, error) {
+	rv, err := mapValue(m)
+	if err != nil {
+		return nil, err
+	}
+	rvKeys := rv.MapKeys()
+	strs := make([]string, len(rvKeys))
+	for i, k := range rvKeys {
+		strs[i] = k.String()
+	}
+	sort.Strings(strs)
+	return strs, nil
+}
+
+func hasKey(m interface{}, key string) (bool, error) {
+	rv, err := mapValue(m)
+	if err != nil {
+		return false, err
+	}
+	return rv.MapIndex(reflect.ValueOf(key).Convert(rv.Type().Key())).IsValid(), nil
+}
+
+func get(m interface{}, key string) (interface{}, error) {
+	rv, err := mapValue(m)
+	if err != nil {
+		return nil, err
+	}
+	if v := rv.MapIndex(reflect.ValueOf(key).Convert(rv.Type().Key())); v.IsValid() {
+		return v.Interface(), nil
+	}
+	return nil, nil
+}
+
+func toJSON(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+func toPrettyJSON(v interface{}) (string, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	return string(data), err
+}
+
+func fromJSON(s string) (interface{}, error) {
+	var v interface{}
+	err := json.Unmarshal([]byte(s), &v)
+	return v, err
+}
+
+func toYAML(v interface{}) (string, error) {
+	// Round trip through JSON, so that types' JSON marshalling (e.g. of
+	// appc schema types) and field names are respected.
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	var generic interface{}
+	if err := yaml.Unmarshal(data, &generic); err != nil {
+		return "", err
+	}
+	data, err = yaml.Marshal(generic)
+	return strings.TrimSuffix(string(data), "\n"), err
+}
+
+// fromYAML parses YAML document, with mappings converted to
+// map[string]interface{} (like fromJson's objects), so that keys,
+// hasKey, and get work on them
+func fromYAML(s string) (interface{}, error) {
+	var v interface{}
+	err := yaml.Unmarshal([]byte(s), &v)
+	return stringKeys(v), err
+}
+
+// stringKeys recursively converts YAML mappings to maps with string
+// keys
+func stringKeys(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case map[interface{}]interface{}:
+		rv := make(map[string]interface{}, len(vv))
+		for k, elt := range vv {
+			rv[fmt.Sprint(k)] = stringKeys(elt)
+		}
+		return rv
+	case []interface{}:
+		for i, elt := range vv {
+			vv[i] = stringKeys(elt)
+		}
+	}
+	return v
+}
+
+func b64dec(s string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(s)
+	return string(data), err
+}
+
+func toInt(v interface{}) (int64, error) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), nil
+	case int8:
+		return int64(n), nil
+	case int16:
+		return int64(n), nil
+	case int32:
+		return int64(n), nil
+	case int64:
+		return n, nil
+	case uint:
+		return int64(n), nil
+	case uint8:
+		return int64(n), nil
+	case uint16:
+		return int64(n), nil
+	case uint32:
+		return int64(n), nil
+	case uint64:
+		return int64(n), nil
+	case float32:
+		return int64(n), nil
+	case float64:
+		return int64(n), nil
+	case string:
+		i, err := strconv.ParseInt(strings.TrimSpace(n), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("not an integer: %q", n)
+		}
+		return i, nil
+	}
+	return 0, fmt.Errorf("not an integer: %#v", v)
+}
+
+func toFloat(v interface{}) (float64, error) {
+	switch n := v.(type) {
+	case float32:
+		return float64(n), nil
+	case float64:
+		return n, nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		if err != nil {
+			return 0, fmt.Errorf("not a number: %q", n)
+		}
+		return f, nil
+	}
+	i, err := toInt(v)
+	if err != nil {
+		return 0, fmt.Errorf("not a number: %#v", v)
+	}
+	return float64(i), nil
+}
+
+// intOp returns template function applying op to arguments converted
+// to integers; if divides is true, op fails when b is zero
+func intOp(op func(a, b int64) int64, divides bool) func(a, b interface{}) (int64, error) {
+	return func(a, b interface{}) (int64, error) {
+		ia, err := toInt(a)
+		if err != nil {
+			return 0, err
+		}
+		ib, err := toInt(b)
+		if err != nil {
+			return 0, err
+		}
+		if divides && ib == 0 {
+			return 0, errors.New("division by zero")
+		}
+		return op(ia, ib), nil
+	}
+}
+
+func minMax(better func(a, b int64) bool) func(a interface{}, more ...interface{}) (int64, error) {
+	return func(a interface{}, more ...interface{}) (int64, error) {
+		rv, err := toInt(a)
+		if err != nil {
+			return 0, err
+		}
+		for _, v := range more {
+			i, err := toInt(v)
+			if err != nil {
+				return 0, err
+			}
+			if better(i, rv) {
+				rv = i
+			}
+		}
+		return rv, nil
+	}
+}
+
+func floatOp(op func(a, b float64) float64) func(a, b interface{}) (float64, error) {
+	return func(a, b interface{}) (float64, error) {
+		fa, err := toFloat(a)
+		if err != nil {
+			return 0, err
+		}
+		fb, err := toFloat(b)
+		if err != nil {
+			return 0, err
+		}
+		return op(fa, fb), nil
+	}
+}
+
+func toTime(v interface{}) (time.Time, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, nil
+	case *time.Time:
+		return *t, nil
+	case string:
+		return time.Parse(time.RFC3339Nano, t)
+	}
+	if i, err := toInt(v); err == nil {
+		return time.Unix(i, 0), nil
+	}
+	return time.Time{}, fmt.Errorf("not a time: %#v", v)
+}
+
+func date(layout string, v interface{}) (string, error) {
+	t, err := toTime(v)
+	if err != nil {
+		return "", err
+	}
+	return t.Format(layout), nil
+}
+
+func unixTime(v interface{}) (int64, error) {
+	t, err := toTime(v)
+	if err != nil {
+		return 0, err
+	}
+	return t.Unix(), nil
+}
@@ -0,0 +1,77 @@
+package mdc
+
+import (
+	"bytes"
+	"testing"
+)
+
+var funcTests = []struct{ template, expected string }{
+	{`{{"" | default "foo"}} {{"bar" | default "foo"}} {{0 | default 5}} {{list | default "empty"}}`, `foo bar 5 empty`},
+	{`{{.PodAnnotation "whatever" | default "none"}}`, `none`},
+	{`{{upper "foo"}} {{lower "FOO"}} {{title "foo bar"}}`, `FOO foo Foo Bar`},
+	{`[{{trim "  foo \n"}}] {{trimPrefix "postgresql/" "postgresql/host"}} {{trimSuffix ".conf" "app.conf"}}`, `[foo] host app`},
+	{`{{replace "/" "_" "postgresql/host/name"}}`, `postgresql_host_name`},
+	{`{{contains "gres" "postgresql"}} {{hasPrefix "post" "postgresql"}} {{hasSuffix "sql" "postgresql"}} {{contains "x" "postgresql"}}`, `true true true false`},
+	{`{{repeat 3 "ab"}}`, `ababab`},
+	{`{{range split "," "a,b,c"}}[{{.}}]{{end}}`, `[a][b][c]`},
+	{`{{split "," "a,b,c" | join ";"}} {{list 1 2 3 | join "+"}}`, `a;b;c 1+2+3`},
+	{`{{quote "foo \"bar\""}} {{squote "it's"}}`, `"foo \"bar\"" 'it'\''s'`},
+	{`{{indent 2 "foo\nbar"}}|{{nindent 2 "foo"}}`, "  foo\n  bar|\n  foo"},
+	{`{{first (list 1 2 3)}} {{last (list 1 2 3)}} {{rest (list 1 2 3)}}`, `1 3 [2 3]`},
+	{`{{append (list 1 2) 3 4}} {{has 2 (list 1 2)}} {{has 5 (list 1 2)}}`, `[1 2 3 4] true false`},
+	{`{{split "," "b,a,b,c" | uniq}} {{split "," "b,a,c" | sortAlpha}}`, `[b a c] [a b c]`},
+	{`{{$d := dict "b" 2 "a" 1}}{{keys $d}} {{hasKey $d "a"}} {{hasKey $d "x"}} {{get $d "b"}}`, `[a b] true false 2`},
+	{`{{dict "a" (list 1 "two") | toJson}} {{(fromJson "{\"a\": [1, 2]}").a}}`, `{"a":[1,"two"]} [1 2]`},
+	{`{{dict "a" 1 | toPrettyJson}}`, "{\n  \"a\": 1\n}"},
+	{`{{dict "b" (list 1 2) "a" "x" | toYaml}}`, "a: x\nb:\n- 1\n- 2"},
+	{`{{(fromYaml "a: [1, 2]").a}}`, `[1 2]`},
+	{`{{.PodAnnotations | toYaml}}`, "- name: ip-address\n  value: 10.1.2.3\n- name: postgresql/host\n  value: db.example.com\n- name: postgresql/port\n  value: \"5432\""},
+	{`{{b64enc "foo:bar"}} {{b64dec "Zm9vOmJhcg=="}}`, `Zm9vOmJhcg== foo:bar`},
+	{`{{toInt "42"}} {{toInt 4.2}} {{toFloat "1.5"}}`, `42 4 1.5`},
+	{`{{toInt "010"}} {{toInt "08"}} {{add "09" 1}}`, `10 8 10`},
+	{`{{keys (fromYaml "b: 1\na: {c: 2}")}} {{hasKey (fromYaml "a: 1") "a"}} {{get (fromYaml "a: {c: 2}").a "c"}}`, `[a b] true 2`},
+	{`{{(index (fromYaml "- {x: 1}") 0).x}}`, `1`},
+	{`{{add 1 "2"}} {{sub 5 3}} {{mul "6" 7}} {{div 7 2}} {{mod 7 2}}`, `3 2 42 3 1`},
+	{`{{max 1 5 "3"}} {{min 4 2 8}}`, `5 2`},
+	{`{{addf 1 "0.5"}} {{subf 1 0.25}} {{mulf "1.5" 2}} {{divf 1 4}}`, `1.5 0.75 3 0.25`},
+	{`{{date "2006-01-02" "2014-10-27T19:32:27.67021798Z"}} {{unixTime "2014-10-27T19:32:27Z"}}`, `2014-10-27 1414438347`},
+	{`{{(parseTime "2006-01-02" "2016-05-15").Year}} {{duration "1h30m"}} {{gt (now.Year) 2015}}`, `2016 1h30m0s true`},
+}
+
+var funcErrorTests = []string{
+	`{{toInt "ten"}}`,
+	`{{add 1 "two"}}`,
+	`{{div 1 0}}`,
+	`{{mod 1 0}}`,
+	`{{toFloat "x"}}`,
+	`{{dict "a"}}`,
+	`{{fromJson "{"}}`,
+	`{{b64dec "!"}}`,
+	`{{join "," "not a list"}}`,
+	`{{keys "not a map"}}`,
+	`{{date "2006" "yesterday"}}`,
+}
+
+func TestFuncMap(t *testing.T) {
+	mdc := newTestClient(t)
+
+	for _, ft := range funcTests {
+		out := &bytes.Buffer{}
+		if tmpl, err := NewTemplate("test").Parse(ft.template); err != nil {
+			t.Errorf("%v: parse error: %v", ft.template, err)
+		} else if err := tmpl.Execute(out, mdc); err != nil {
+			t.Errorf("%v: execute error: %v", ft.template, err)
+		} else if actual := out.String(); actual != ft.expected {
+			t.Errorf("%v: got %#v, expected %#v", ft.template, actual, ft.expected)
+		}
+	}
+
+	for _, tmplText := range funcErrorTests {
+		out := &bytes.Buffer{}
+		if tmpl, err := NewTemplate("test").Parse(tmplText); err != nil {
+			t.Errorf("%v: parse error: %v", tmplText, err)
+		} else if err := tmpl.Execute(out, mdc); err == nil {
+			t.Errorf("%v: expected error, got %#v", tmplText, out.String())
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package mdc
+
+import (
+	"github.com/appc/spec/schema/types"
+	"k8s.io/kubernetes/pkg/api/resource"
+)
+
+// Isolator returns the app's isolator of given name, from the pod
+// manifest's app if it sets it, or else from the image manifest's app;
+// nil is returned if neither sets it.
+func (app *App) Isolator(name string) (*types.Isolator, error) {
+	id, err := types.NewACIdentifier(name)
+	if err != nil {
+		return nil, nil
+	}
+
+	ra, err := app.runtimeAppOverride()
+	if err != nil {
+		return nil, err
+	}
+	if ra != nil {
+		if iso := ra.Isolators.GetByName(*id); iso != nil {
+			return iso, nil
+		}
+	}
+
+	ia, err := app.imageApp()
+	if err != nil {
+		return nil, err
+	}
+	if ia != nil {
+		return ia.Isolators.GetByName(*id), nil
+	}
+	return nil, nil
+}
+
+// resource returns limit or request of a resource isolator, or nil if
+// it's not set
+func (app *App) resource(name string, limit bool) (*resource.Quantity, error) {
+	iso, err := app.Isolator(name)
+	if iso == nil || err != nil {
+		return nil, err
+	}
+	res, ok := iso.Value().(types.Resource)
+	if !ok {
+		return nil, nil
+	}
+	if limit {
+		return res.Limit(), nil
+	}
+	return res.Request(), nil
+}
+
+func (app *App) resourceValue(name string, limit bool) (int64, error) {
+	q, err := app.resource(name, limit)
+	if q == nil || err != nil {
+		return 0, err
+	}
+	return q.Value(), nil
+}
+
+func (app *App) resourceMilliValue(name string, limit bool) (float64, error) {
+	q, err := app.resource(name, limit)
+	if q == nil || err != nil {
+		return 0, err
+	}
+	return float64(q.MilliValue()) / 1000, nil
+}
+
+// MemoryLimitBytes returns the app's memory limit in bytes, or 0 if
+// there is no limit.
+func (app *App) MemoryLimitBytes() (int64, error) {
+	return app.resourceValue(types.ResourceMemoryName, true)
+}
+
+// MemoryRequestBytes returns the app's requested memory in bytes, or 0
+// if not set.
+func (app *App) MemoryRequestBytes() (int64, error) {
+	return app.resourceValue(types.ResourceMemoryName, false)
+}
+
+// MemoryLimitPercent returns percent of the app's memory limit in
+// bytes, rounded down, or 0 if there is no limit; e.g. to size a heap
+// with 75% of available memory.
+func (app *App) MemoryLimitPercent(percent float64) (int64, error) {
+	limit, err := app.MemoryLimitBytes()
+	return int64(float64(limit) * percent / 100), err
+}
+
+// CPULimit returns the app's CPU limit in cores (possibly fractional),
+// or 0 if there is no limit.
+func (app *App) CPULimit() (float64, error) {
+	return app.resourceMilliValue(types.ResourceCPUName, true)
+}
+
+// CPURequest returns the app's requested CPU in cores, or 0 if not set.
+func (app *App) CPURequest() (float64, error) {
+	return app.resourceMilliValue(types.ResourceCPUName, false)
+}
+
+func (mdc *MDClient) Isolator(name string) (*types.Isolator, error) {
+	return mdc.App(mdc.ACAppName).Isolator(name)
+}
+
+func (mdc *MDClient) MemoryLimitBytes() (int64, error) {
+	return mdc.App(mdc.ACAppName).MemoryLimitBytes()
+}
+
+func (mdc *MDClient) MemoryRequestBytes() (int64, error) {
+	return mdc.App(mdc.ACAppName).MemoryRequestBytes()
+}
+
+func (mdc *MDClient) MemoryLimitPercent(percent float64) (int64, error) {
+	return mdc.App(mdc.ACAppName).MemoryLimitPercent(percent)
+}
+
+func (mdc *MDClient) CPULimit() (float64, error) {
+	return mdc.App(mdc.ACAppName).CPULimit()
+}
+
+func (mdc *MDClient) CPURequest() (float64, error) {
+	return mdc.App(mdc.ACAppName).CPURequest()
+}
@@ -0,0 +1,83 @@
+package mdc
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/appc/spec/schema/types"
+)
+
+func TestIsolators(t *testing.T) {
+	pod := newTestPod(t)
+	mdc, err := NewMDClient(Options{Service: pod, AppName: "reduce-worker"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if limit, err := mdc.MemoryLimitBytes(); err != nil {
+		t.Error("MemoryLimitBytes:", err)
+	} else if limit != 1000000000 {
+		t.Error("Invalid memory limit:", limit)
+	}
+
+	if request, err := mdc.MemoryRequestBytes(); err != nil {
+		t.Error("MemoryRequestBytes:", err)
+	} else if request != 0 {
+		t.Error("Invalid memory request:", request)
+	}
+
+	if cpu, err := mdc.CPULimit(); err != nil {
+		t.Error("CPULimit:", err)
+	} else if cpu != 20 {
+		t.Error("Invalid CPU limit:", cpu)
+	}
+
+	if iso, err := mdc.Isolator("os/linux/capabilities-revoke-set"); err != nil {
+		t.Error("Isolator:", err)
+	} else if iso == nil || iso.Name != "os/linux/capabilities-revoke-set" {
+		t.Errorf("Invalid isolator: %#v", iso)
+	}
+
+	if iso, err := mdc.Isolator("resource/network-bandwidth"); err != nil {
+		t.Error("Isolator:", err)
+	} else if iso != nil {
+		t.Errorf("Expected nil isolator, got: %#v", iso)
+	}
+
+	// Runtime app's isolators take precedence
+	pod.Manifest.Apps[0].App = &types.App{}
+	if err := json.Unmarshal([]byte(`{
+        "exec": ["/usr/bin/reduce-worker"],
+        "user": "100",
+        "group": "300",
+        "isolators": [
+            {"name": "resource/memory", "value": {"request": "1Gi", "limit": "2Gi"}},
+            {"name": "resource/cpu", "value": {"request": "250m", "limit": "1500m"}}
+        ]
+    }`), pod.Manifest.Apps[0].App); err != nil {
+		t.Fatal(err)
+	}
+	mdc, err = NewMDClient(Options{Service: pod, AppName: "reduce-worker"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl, err := NewTemplate("").Parse(`{{.MemoryLimitBytes}} {{.MemoryRequestBytes}} {{.CPULimit}} {{.CPURequest}} -Xmx{{div (.MemoryLimitPercent 75) 1048576}}m`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, mdc); err != nil {
+		t.Error("Execute:", err)
+	} else if expected := "2147483648 1073741824 1.5 0.25 -Xmx1536m"; buf.String() != expected {
+		t.Errorf("got %#v, expected %#v", buf.String(), expected)
+	}
+
+	// No image manifest, no isolators
+	if limit, err := mdc.App("backup").MemoryLimitBytes(); err != nil {
+		t.Error("MemoryLimitBytes:", err)
+	} else if limit != 0 {
+		t.Error("Invalid memory limit:", limit)
+	}
+}
@@ -0,0 +1,151 @@
+package mdc
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/appc/spec/schema/types"
+)
+
+// Annotation layers searched by Lookup.
+const (
+	// LayerApp is current app's runtime annotations from the pod
+	// manifest
+	LayerApp = "app"
+	// LayerPod is pod's annotations
+	LayerPod = "pod"
+	// LayerImage is annotations of current app's image manifest
+	LayerImage = "image"
+)
+
+// DefaultLookupOrder is used when Options.LookupOrder is empty: values
+// set for the app override pod's values, which override defaults from
+// the image manifest.
+var DefaultLookupOrder = []string{LayerApp, LayerPod, LayerImage}
+
+// ParseLookupOrder parses comma-separated list of layer names, as in
+// "app,pod,image". Layers that are not listed are not searched.
+func ParseLookupOrder(s string) ([]string, error) {
+	var order []string
+	for _, layer := range strings.Split(s, ",") {
+		if layer = strings.TrimSpace(layer); layer != "" {
+			order = append(order, layer)
+		}
+	}
+	if err := checkLookupOrder(order); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+func checkLookupOrder(order []string) error {
+	if len(order) == 0 {
+		return fmt.Errorf("empty lookup order")
+	}
+	seen := make(map[string]bool)
+	for _, layer := range order {
+		switch layer {
+		case LayerApp, LayerPod, LayerImage:
+		default:
+			return fmt.Errorf("invalid lookup layer %q (expected %s, %s, or %s)", layer, LayerApp, LayerPod, LayerImage)
+		}
+		if seen[layer] {
+			return fmt.Errorf("lookup layer %q listed twice", layer)
+		}
+		seen[layer] = true
+	}
+	return nil
+}
+
+// LayerValue is an annotation's value in a single lookup layer.
+type LayerValue struct {
+	Layer string `json:"layer"`
+	Value string `json:"value"`
+	Found bool   `json:"found"`
+}
+
+func (mdc *MDClient) layerAnnotations(layer string) (types.Annotations, error) {
+	switch layer {
+	case LayerApp:
+		// Metadata service's app annotations include the image
+		// manifest's ones, so the runtime app's annotations are taken
+		// from the pod manifest instead.
+		ra, err := mdc.App(mdc.ACAppName).RuntimeApp()
+		if ra == nil || err != nil {
+			return nil, err
+		}
+		return ra.Annotations, nil
+	case LayerPod:
+		return mdc.PodAnnotations()
+	case LayerImage:
+		im, err := mdc.AppImageManifest()
+		if IsNotFound(err) {
+			// no image manifest means no image defaults
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return im.Annotations, nil
+	}
+	panic("invalid lookup layer: " + layer)
+}
+
+// LookupLayers returns annotation's value in each layer, in the
+// client's lookup order.
+func (mdc *MDClient) LookupLayers(name string) ([]LayerValue, error) {
+	rv := make([]LayerValue, len(mdc.lookupOrder))
+	for i, layer := range mdc.lookupOrder {
+		anns, err := mdc.layerAnnotations(layer)
+		if err != nil {
+			return nil, err
+		}
+		rv[i].Layer = layer
+		rv[i].Value, rv[i].Found = anns.Get(name)
+	}
+	return rv, nil
+}
+
+// Lookup returns annotation's value from the first layer, in the
+// client's lookup order, that has it, and name of that layer. If no
+// layer has the annotation, *AnnotationNotFoundError is returned.
+func (mdc *MDClient) Lookup(name string) (value, layer string, err error) {
+	for _, layer := range mdc.lookupOrder {
+		anns, err := mdc.layerAnnotations(layer)
+		if err != nil {
+			return "", "", err
+		}
+		if value, found := anns.Get(name); found {
+			return value, layer, nil
+		}
+	}
+	return "", "", &AnnotationNotFoundError{name}
+}
+
+// Annotation returns annotation's value looked up in app, pod, and
+// image manifest annotations (see Lookup), or empty string if none of
+// them has it.
+func (mdc *MDClient) Annotation(name string) (string, error) {
+	value, _, err := mdc.Lookup(name)
+	if _, notFound := err.(*AnnotationNotFoundError); notFound {
+		mdc.missing("", name)
+		return "", nil
+	}
+	return value, err
+}
+
+func (mdc *MDClient) AnnotationOr(name, defaultValue string) (string, error) {
+	value, _, err := mdc.Lookup(name)
+	if _, notFound := err.(*AnnotationNotFoundError); notFound {
+		return defaultValue, nil
+	}
+	return value, err
+}
+
+func (mdc *MDClient) MustAnnotation(name string) (string, error) {
+	value, _, err := mdc.Lookup(name)
+	if _, notFound := err.(*AnnotationNotFoundError); notFound {
+		err = mdc.notFound("", name)
+	}
+	return value, err
+}
@@ -0,0 +1,134 @@
+package mdc
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	pod := newTestPod(t)
+	pod.Manifest.Annotations.Set("foo", "pod-foo")
+	pod.Manifest.Annotations.Set("homepage", "https://pod.example.com")
+
+	mdc, err := NewMDClient(Options{Service: pod, AppName: "reduce-worker"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, expected := range map[string][2]string{
+		"foo":           {"baz", LayerApp},
+		"homepage":      {"https://pod.example.com", LayerPod},
+		"ip-address":    {"10.1.2.3", LayerPod},
+		"documentation": {"https://example.com/docs", LayerImage},
+	} {
+		if value, layer, err := mdc.Lookup(name); err != nil {
+			t.Errorf("Lookup(%q): %v", name, err)
+		} else if value != expected[0] || layer != expected[1] {
+			t.Errorf("Lookup(%q) = %q, %q; expected %q", name, value, layer, expected)
+		}
+	}
+
+	if _, _, err := mdc.Lookup("nonexistent"); err == nil {
+		t.Error("Expected error for missing annotation")
+	} else if _, ok := err.(*AnnotationNotFoundError); !ok {
+		t.Errorf("Expected AnnotationNotFoundError, got: %#v", err)
+	}
+
+	if layers, err := mdc.LookupLayers("homepage"); err != nil {
+		t.Error("LookupLayers:", err)
+	} else if !reflect.DeepEqual(layers, []LayerValue{
+		{LayerApp, "", false},
+		{LayerPod, "https://pod.example.com", true},
+		{LayerImage, "https://example.com", true},
+	}) {
+		t.Errorf("Invalid layers: %#v", layers)
+	}
+
+	tmpl, err := NewTemplate("").Parse(`{{.Annotation "foo"}} {{.Annotation "documentation"}} [{{.Annotation "nonexistent"}}] {{.AnnotationOr "nonexistent" "dflt"}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, mdc); err != nil {
+		t.Error("Execute:", err)
+	} else if buf.String() != "baz https://example.com/docs [] dflt" {
+		t.Error("Invalid output:", buf.String())
+	}
+
+	// Image defaults first, pod overrides them, app layer is not searched
+	mdc, err = NewMDClient(Options{Service: pod, AppName: "reduce-worker", LookupOrder: []string{LayerImage, LayerPod}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, layer, err := mdc.Lookup("homepage"); err != nil {
+		t.Error("Lookup:", err)
+	} else if value != "https://example.com" || layer != LayerImage {
+		t.Errorf("Lookup = %q, %q", value, layer)
+	}
+	if value, layer, err := mdc.Lookup("foo"); err != nil {
+		t.Error("Lookup:", err)
+	} else if value != "pod-foo" || layer != LayerPod {
+		t.Errorf("Lookup = %q, %q", value, layer)
+	}
+
+	// Missing image manifest is an empty layer
+	backup, err := NewMDClient(Options{Service: pod, AppName: "backup"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, err := backup.Annotation("foo"); err != nil {
+		t.Error("Annotation:", err)
+	} else if value != "pod-foo" {
+		t.Error("Invalid value:", value)
+	}
+
+	if _, err := NewMDClient(Options{Service: pod, AppName: "reduce-worker", LookupOrder: []string{"app", "nope"}}); err == nil {
+		t.Error("Expected error for invalid lookup order")
+	}
+}
+
+func TestParseLookupOrder(t *testing.T) {
+	if order, err := ParseLookupOrder(" pod, image "); err != nil {
+		t.Error("ParseLookupOrder:", err)
+	} else if !reflect.DeepEqual(order, []string{LayerPod, LayerImage}) {
+		t.Errorf("Invalid order: %#v", order)
+	}
+
+	for _, s := range []string{"", "app,app", "app,pod,manifest"} {
+		if _, err := ParseLookupOrder(s); err == nil {
+			t.Errorf("Expected error for %q", s)
+		}
+	}
+}
+
+func TestLookupMetadataService(t *testing.T) {
+	// Metadata service merges image manifest's annotations into app's
+	// annotations; they must still be found in the image layer.
+	mdc, err := NewMDClient(Options{MetadataURL: mds.URL, AppName: "reduce-worker"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, expected := range map[string][2]string{
+		"foo":        {"baz", LayerApp},
+		"ip-address": {"10.1.2.3", LayerPod},
+		"homepage":   {"https://example.com", LayerImage},
+	} {
+		if value, layer, err := mdc.Lookup(name); err != nil {
+			t.Errorf("Lookup(%q): %v", name, err)
+		} else if value != expected[0] || layer != expected[1] {
+			t.Errorf("Lookup(%q) = %q, %q; expected %q", name, value, layer, expected)
+		}
+	}
+
+	if layers, err := mdc.LookupLayers("homepage"); err != nil {
+		t.Error("LookupLayers:", err)
+	} else if !reflect.DeepEqual(layers, []LayerValue{
+		{LayerApp, "", false},
+		{LayerPod, "", false},
+		{LayerImage, "https://example.com", true},
+	}) {
+		t.Errorf("Invalid layers: %#v", layers)
+	}
+}
@@ -0,0 +1,157 @@
+package mdc
+
+import "github.com/appc/spec/schema/types"
+
+// Mount is a mount point declared by an app.
+type Mount struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	// ReadOnly is true if either the mount point or the volume backing
+	// it is read-only.
+	ReadOnly bool `json:"readOnly"`
+	// Volume is the pod's volume backing the mount point, or nil if it
+	// is not backed by a volume.
+	Volume *types.Volume `json:"volume,omitempty"`
+}
+
+// MountPointNotFoundError is returned when app does not declare the
+// mount point.
+type MountPointNotFoundError struct {
+	Name string
+}
+
+func (err *MountPointNotFoundError) Error() string {
+	return "mount point not declared: " + err.Name
+}
+
+// Mounts returns mount points declared by the image manifest's app,
+// overridden and extended by the pod manifest's app, with volumes
+// backing them. A mount point is backed by the volume that the pod
+// manifest's app mounts on its path or, if there's no such mount, by
+// the pod's volume of the same name as the mount point (like rkt does).
+func (app *App) Mounts() ([]Mount, error) {
+	var mps []types.MountPoint
+
+	ia, err := app.imageApp()
+	if err != nil {
+		return nil, err
+	}
+	if ia != nil {
+		mps = append(mps, ia.MountPoints...)
+	}
+
+	ra, err := app.RuntimeApp()
+	if err != nil {
+		return nil, err
+	}
+	if ra != nil && ra.App != nil {
+	override:
+		for _, mp := range ra.App.MountPoints {
+			for i := range mps {
+				if mps[i].Name == mp.Name {
+					mps[i] = mp
+					continue override
+				}
+			}
+			mps = append(mps, mp)
+		}
+	}
+
+	pm, err := app.mdc.PodManifest()
+	if err != nil {
+		return nil, err
+	}
+	volume := func(name types.ACName) *types.Volume {
+		for i := range pm.Volumes {
+			if pm.Volumes[i].Name == name {
+				return &pm.Volumes[i]
+			}
+		}
+		return nil
+	}
+
+	rv := make([]Mount, len(mps))
+	for i, mp := range mps {
+		rv[i] = Mount{Name: mp.Name.String(), Path: mp.Path}
+		if ra != nil {
+			for _, m := range ra.Mounts {
+				if m.Path == mp.Path {
+					rv[i].Volume = volume(m.Volume)
+					break
+				}
+			}
+		}
+		if rv[i].Volume == nil {
+			rv[i].Volume = volume(mp.Name)
+		}
+		rv[i].ReadOnly = mp.ReadOnly || rv[i].Volume != nil && rv[i].Volume.ReadOnly != nil && *rv[i].Volume.ReadOnly
+	}
+	return rv, nil
+}
+
+// MountInfo returns the app's mount point of given name, or
+// *MountPointNotFoundError.
+func (app *App) MountInfo(name string) (*Mount, error) {
+	mounts, err := app.Mounts()
+	if err != nil {
+		return nil, err
+	}
+	for i := range mounts {
+		if mounts[i].Name == name {
+			return &mounts[i], nil
+		}
+	}
+	return nil, &MountPointNotFoundError{name}
+}
+
+// MountPath returns path of the app's mount point of given name.
+func (app *App) MountPath(name string) (string, error) {
+	mount, err := app.MountInfo(name)
+	if err != nil {
+		return "", err
+	}
+	return mount.Path, nil
+}
+
+// HasVolume returns true if the app declares the mount point, and it
+// is backed by a volume.
+func (app *App) HasVolume(name string) (bool, error) {
+	mount, err := app.MountInfo(name)
+	if _, notFound := err.(*MountPointNotFoundError); notFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return mount.Volume != nil, nil
+}
+
+// VolumeReadOnly returns true if the app's mount point of given name
+// or the volume backing it is read-only.
+func (app *App) VolumeReadOnly(name string) (bool, error) {
+	mount, err := app.MountInfo(name)
+	if err != nil {
+		return false, err
+	}
+	return mount.ReadOnly, nil
+}
+
+func (mdc *MDClient) Mounts() ([]Mount, error) {
+	return mdc.App(mdc.ACAppName).Mounts()
+}
+
+func (mdc *MDClient) MountInfo(name string) (*Mount, error) {
+	return mdc.App(mdc.ACAppName).MountInfo(name)
+}
+
+func (mdc *MDClient) MountPath(name string) (string, error) {
+	return mdc.App(mdc.ACAppName).MountPath(name)
+}
+
+func (mdc *MDClient) HasVolume(name string) (bool, error) {
+	return mdc.App(mdc.ACAppName).HasVolume(name)
+}
+
+func (mdc *MDClient) VolumeReadOnly(name string) (bool, error) {
+	return mdc.App(mdc.ACAppName).VolumeReadOnly(name)
+}
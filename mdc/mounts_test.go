@@ -0,0 +1,91 @@
+package mdc
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/appc/spec/schema"
+	"github.com/appc/spec/schema/types"
+)
+
+func TestMounts(t *testing.T) {
+	pod := newTestPod(t)
+	pod.ImageManifests["reduce-worker"].App.MountPoints = []types.MountPoint{
+		{Name: "data", Path: "/var/lib/reduce"},
+		{Name: "config", Path: "/etc/reduce", ReadOnly: true},
+		{Name: "cache", Path: "/var/cache/reduce"},
+	}
+	if err := json.Unmarshal([]byte(`[
+        {"name": "work", "kind": "host", "source": "/srv/reduce", "readOnly": true},
+        {"name": "config", "kind": "empty"},
+        {"name": "unused", "kind": "empty"}
+    ]`), &pod.Manifest.Volumes); err != nil {
+		t.Fatal(err)
+	}
+	pod.Manifest.Apps[0].Mounts = []schema.Mount{{Volume: "work", Path: "/var/lib/reduce"}}
+
+	mdc, err := NewMDClient(Options{Service: pod, AppName: "reduce-worker"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if path, err := mdc.MountPath("data"); err != nil {
+		t.Error("MountPath:", err)
+	} else if path != "/var/lib/reduce" {
+		t.Error("Invalid mount path:", path)
+	}
+
+	if _, err := mdc.MountPath("logs"); err == nil {
+		t.Error("Expected error for undeclared mount point")
+	} else if _, ok := err.(*MountPointNotFoundError); !ok {
+		t.Errorf("Expected MountPointNotFoundError, got: %#v", err)
+	}
+
+	for name, expected := range map[string]bool{"data": true, "config": true, "cache": false, "logs": false} {
+		if has, err := mdc.HasVolume(name); err != nil {
+			t.Errorf("HasVolume(%q): %v", name, err)
+		} else if has != expected {
+			t.Errorf("HasVolume(%q): expected %v, got %v", name, expected, has)
+		}
+	}
+
+	for name, expected := range map[string]bool{"data": true, "config": true, "cache": false} {
+		if ro, err := mdc.VolumeReadOnly(name); err != nil {
+			t.Errorf("VolumeReadOnly(%q): %v", name, err)
+		} else if ro != expected {
+			t.Errorf("VolumeReadOnly(%q): expected %v, got %v", name, expected, ro)
+		}
+	}
+
+	if mount, err := mdc.MountInfo("data"); err != nil {
+		t.Error("MountInfo:", err)
+	} else if mount.Volume == nil || mount.Volume.Name != "work" || mount.Volume.Source != "/srv/reduce" {
+		t.Errorf("Invalid volume: %#v", mount.Volume)
+	}
+
+	// Runtime app override
+	pod.Manifest.Apps[0].App = &types.App{}
+	if err := json.Unmarshal([]byte(`{
+        "exec": ["/usr/bin/reduce-worker"],
+        "user": "100",
+        "group": "300",
+        "mountPoints": [{"name": "cache", "path": "/tmp/cache"}, {"name": "unused", "path": "/unused"}]
+    }`), pod.Manifest.Apps[0].App); err != nil {
+		t.Fatal(err)
+	}
+
+	mdc, err = NewMDClient(Options{Service: pod, AppName: "reduce-worker"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if mounts, err := mdc.Mounts(); err != nil {
+		t.Error("Mounts:", err)
+	} else if len(mounts) != 4 {
+		t.Errorf("Invalid mounts: %#v", mounts)
+	} else if mounts[2].Name != "cache" || mounts[2].Path != "/tmp/cache" {
+		t.Errorf("Invalid overridden mount: %#v", mounts[2])
+	} else if mounts[3].Name != "unused" || mounts[3].Volume == nil || mounts[3].Volume.Kind != "empty" {
+		t.Errorf("Invalid added mount: %#v", mounts[3])
+	}
+}
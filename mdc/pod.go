@@ -0,0 +1,230 @@
+package mdc
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/appc/spec/schema"
+	"github.com/appc/spec/schema/types"
+)
+
+// Pod is a Service that answers metadata requests from static
+// manifests, without a running metadata service.
+type Pod struct {
+	UUID     string
+	Manifest *schema.PodManifest
+	// ImageManifests of the pod's apps, by app name.
+	ImageManifests map[string]*schema.ImageManifest
+	// HMACKey is used to sign content; a random key is generated if
+	// not set.
+	HMACKey []byte
+	keyOnce sync.Once
+}
+
+// DefaultPodUUID is used by LoadOffline when no UUID is given.
+const DefaultPodUUID = "00000000-0000-0000-0000-000000000000"
+
+// Request implements Service.
+func (p *Pod) Request(method, path string, form url.Values) (int, []byte, error) {
+	switch path {
+	case "pod/hmac/sign":
+		if method != "POST" {
+			return http.StatusMethodNotAllowed, nil, nil
+		}
+		return http.StatusOK, []byte(p.sign(form.Get("content"))), nil
+	case "pod/hmac/verify":
+		if method != "POST" {
+			return http.StatusMethodNotAllowed, nil, nil
+		}
+		if form.Get("uid") != p.UUID || !hmac.Equal([]byte(form.Get("signature")), []byte(p.sign(form.Get("content")))) {
+			return http.StatusForbidden, nil, nil
+		}
+		return http.StatusOK, nil, nil
+	}
+
+	if method != "GET" {
+		return http.StatusMethodNotAllowed, nil, nil
+	}
+
+	switch path {
+	case "pod/uuid":
+		return http.StatusOK, []byte(p.UUID), nil
+	case "pod/manifest":
+		return marshalResponse(p.Manifest)
+	case "pod/annotations":
+		return marshalResponse(nonNil(p.Manifest.Annotations))
+	}
+
+	if !strings.HasPrefix(path, "apps/") {
+		return http.StatusNotFound, nil, nil
+	}
+	pieces := strings.SplitN(strings.TrimPrefix(path, "apps/"), "/", 2)
+	if len(pieces) != 2 {
+		return http.StatusNotFound, nil, nil
+	}
+
+	name, err := types.NewACName(pieces[0])
+	if err != nil {
+		return http.StatusNotFound, nil, nil
+	}
+	app := p.Manifest.Apps.Get(*name)
+	if app == nil {
+		return http.StatusNotFound, nil, nil
+	}
+
+	switch pieces[1] {
+	case "annotations":
+		var imageAnns types.Annotations
+		if im := p.ImageManifests[name.String()]; im != nil {
+			imageAnns = im.Annotations
+		}
+		return marshalResponse(mergeAnnotations(app.Annotations, imageAnns))
+	case "image/id":
+		return http.StatusOK, []byte(app.Image.ID.String()), nil
+	case "image/manifest":
+		if im := p.ImageManifests[name.String()]; im != nil {
+			return marshalResponse(im)
+		}
+	}
+	return http.StatusNotFound, nil, nil
+}
+
+func (p *Pod) sign(content string) string {
+	p.keyOnce.Do(func() {
+		if p.HMACKey == nil {
+			p.HMACKey = make([]byte, sha512.Size)
+			if _, err := rand.Read(p.HMACKey); err != nil {
+				panic(err)
+			}
+		}
+	})
+	mac := hmac.New(sha512.New, p.HMACKey)
+	mac.Write([]byte(content))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func marshalResponse(v interface{}) (int, []byte, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, body, nil
+}
+
+// mergeAnnotations returns app's runtime annotations followed by its
+// image manifest's annotations that are not overridden by them, like
+// the metadata service's apps/NAME/annotations does
+func mergeAnnotations(runtime, image types.Annotations) types.Annotations {
+	rv := append(types.Annotations{}, runtime...)
+	for _, ann := range image {
+		if _, found := runtime.Get(ann.Name.String()); !found {
+			rv = append(rv, ann)
+		}
+	}
+	return rv
+}
+
+func nonNil(anns types.Annotations) types.Annotations {
+	if anns == nil {
+		return types.Annotations{}
+	}
+	return anns
+}
+
+// LoadOffline sets opts up to read metadata from files in dir instead
+// of the metadata service:
+//
+//	pod-manifest.json   -- pod manifest
+//	image-manifest.json -- current app's image manifest
+//	uuid                -- pod UUID (optional, DefaultPodUUID if missing)
+//	app-name            -- current app's name (optional if opts.AppName is set)
+//
+// App annotations come from the pod manifest's apps, merged with the
+// image manifest's annotations like the metadata service does, and
+// image IDs come from the pod manifest's apps.
+func (opts *Options) LoadOffline(dir string) error {
+	if opts.AppName == "" {
+		if appName, err := readOptionalFile(filepath.Join(dir, "app-name")); err != nil {
+			return err
+		} else if appName == "" {
+			return ErrNoAppName
+		} else {
+			opts.AppName = appName
+		}
+	}
+
+	pm, err := LoadPodManifest(filepath.Join(dir, "pod-manifest.json"))
+	if err != nil {
+		return err
+	}
+
+	im, err := LoadImageManifest(filepath.Join(dir, "image-manifest.json"))
+	if err != nil {
+		return err
+	}
+
+	pod := &Pod{
+		Manifest:       pm,
+		ImageManifests: map[string]*schema.ImageManifest{opts.AppName: im},
+	}
+
+	if uuid, err := readOptionalFile(filepath.Join(dir, "uuid")); err != nil {
+		return err
+	} else if uuid == "" {
+		pod.UUID = DefaultPodUUID
+	} else {
+		pod.UUID = uuid
+	}
+
+	opts.Service = pod
+	return nil
+}
+
+// LoadPodManifest reads pod manifest from JSON file at path.
+func LoadPodManifest(path string) (*schema.PodManifest, error) {
+	pm := &schema.PodManifest{}
+	if err := readJSONFile(path, pm); err != nil {
+		return nil, err
+	}
+	return pm, nil
+}
+
+// LoadImageManifest reads image manifest from JSON file at path.
+func LoadImageManifest(path string) (*schema.ImageManifest, error) {
+	im := &schema.ImageManifest{}
+	if err := readJSONFile(path, im); err != nil {
+		return nil, err
+	}
+	return im, nil
+}
+
+func readJSONFile(path string, v interface{}) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return &os.PathError{Op: "parse", Path: path, Err: err}
+	}
+	return nil
+}
+
+// readOptionalFile returns trimmed contents of file at path, or empty
+// string if it does not exist
+func readOptionalFile(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	return strings.TrimSpace(string(data)), err
+}
@@ -0,0 +1,209 @@
+package mdc
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"text/template"
+
+	"github.com/appc/spec/schema"
+)
+
+func newTestPod(t *testing.T) *Pod {
+	pod := &Pod{
+		UUID:           pod_uuid,
+		Manifest:       &schema.PodManifest{},
+		ImageManifests: map[string]*schema.ImageManifest{"reduce-worker": {}},
+	}
+	if err := json.Unmarshal([]byte(pod_manifest), pod.Manifest); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(image_manifest), pod.ImageManifests["reduce-worker"]); err != nil {
+		t.Fatal(err)
+	}
+	return pod
+}
+
+func TestPod(t *testing.T) {
+	mdc, err := NewMDClient(Options{Service: newTestPod(t), AppName: "reduce-worker"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if uuid, err := mdc.UUID(); err != nil {
+		t.Error("UUID:", err)
+	} else if uuid != pod_uuid {
+		t.Error("Invalid UUID:", uuid)
+	}
+
+	if pm, err := mdc.PodManifest(); err != nil {
+		t.Error("PodManifest:", err)
+	} else if len(pm.Apps) != 2 {
+		t.Error("Invalid pod manifest apps:", pm.Apps)
+	}
+
+	if val, err := mdc.PodAnnotation("ip-address"); err != nil {
+		t.Error("PodAnnotation:", err)
+	} else if val != "10.1.2.3" {
+		t.Error("Invalid annotation value:", val)
+	}
+
+	if id, err := mdc.AppImageID(); err != nil {
+		t.Error("AppImageID:", err)
+	} else if id != "sha512-8d3fffddf79e9a232ffd19f9ccaa4d6b37a6a243dbe0f23137b108a043d9da13121a9b505c804956b22e93c7f93969f4a7ba8ddea45bf4aab0bebc8f814e0990" {
+		t.Error("Invalid app image ID:", id)
+	}
+
+	if im, err := mdc.AppImageManifest(); err != nil {
+		t.Error("AppImageManifest:", err)
+	} else if im.Name != "example.com/reduce-worker" {
+		t.Error("Invalid image manifest name:", im.Name)
+	}
+
+	if val, err := mdc.AppAnnotation("foo"); err != nil {
+		t.Error("AppAnnotation:", err)
+	} else if val != "baz" {
+		t.Error("Invalid annotation value:", val)
+	}
+
+	// App annotations include image manifest's ones, like the
+	// metadata service's
+	live, err := NewMDClient(Options{MetadataURL: mds.URL, AppName: "reduce-worker"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if anns, err := mdc.AppAnnotations(); err != nil {
+		t.Error("AppAnnotations:", err)
+	} else if expected, err := live.AppAnnotations(); err != nil {
+		t.Error("AppAnnotations (metadata service):", err)
+	} else if !reflect.DeepEqual(anns, expected) {
+		t.Errorf("App annotations differ from metadata service's: %#v", anns)
+	}
+
+	sig, err := mdc.Sign("some content")
+	if err != nil {
+		t.Fatal("Sign:", err)
+	}
+	if ok, err := mdc.Verify("some content", sig, pod_uuid); err != nil {
+		t.Error("Verify:", err)
+	} else if !ok {
+		t.Error("Valid signature not verified")
+	}
+	if ok, err := mdc.Verify("other content", sig, pod_uuid); err != nil {
+		t.Error("Verify:", err)
+	} else if ok {
+		t.Error("Signature of other content verified")
+	}
+
+	out := &bytes.Buffer{}
+	tmpl := template.Must(template.New("appc-metadata-client").Parse(templateText))
+	if err := tmpl.Execute(out, mdc); err != nil {
+		t.Error("Error rendering template:", err)
+	} else if actual := out.String(); actual != templateExpected {
+		t.Errorf("Rendered template: got %#v, but expected %#v", actual, templateExpected)
+	}
+
+	mdc, err = NewMDClient(Options{Service: mdc.service, AppName: "backup"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if anns, err := mdc.AppAnnotations(); err != nil {
+		t.Error("AppAnnotations:", err)
+	} else if len(anns) != 0 {
+		t.Error("Unexpected backup app annotations:", anns)
+	}
+
+	if _, err := mdc.AppImageManifestJSON(); !IsNotFound(err) {
+		t.Error("Expected not found error, got:", err)
+	}
+}
+
+func TestLoadOffline(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mdc-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for name, contents := range map[string]string{
+		"pod-manifest.json":   pod_manifest,
+		"image-manifest.json": image_manifest,
+		"app-name":            "reduce-worker\n",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts := Options{}
+	if err := opts.LoadOffline(dir); err != nil {
+		t.Fatal("LoadOffline:", err)
+	}
+
+	if opts.AppName != "reduce-worker" {
+		t.Error("Invalid app name:", opts.AppName)
+	}
+
+	mdc, err := NewMDClient(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if uuid, err := mdc.UUID(); err != nil {
+		t.Error("UUID:", err)
+	} else if uuid != DefaultPodUUID {
+		t.Error("Invalid UUID:", uuid)
+	}
+
+	if val, err := mdc.AppAnnotation("foo"); err != nil {
+		t.Error("AppAnnotation:", err)
+	} else if val != "baz" {
+		t.Error("Invalid annotation value:", val)
+	}
+
+	// Image manifest's annotations are app annotations, like in a pod
+	if val, err := mdc.AppAnnotation("homepage"); err != nil {
+		t.Error("AppAnnotation:", err)
+	} else if val != "https://example.com" {
+		t.Error("Invalid annotation value:", val)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "image-manifest.json")); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&Options{}).LoadOffline(dir); !os.IsNotExist(err) {
+		t.Error("Expected missing image manifest error, got:", err)
+	}
+}
+
+func TestPodAppAnnotationsOverride(t *testing.T) {
+	pod := newTestPod(t)
+	pod.Manifest.Apps[0].Annotations.Set("homepage", "https://app.example.com")
+	mdc, err := NewMDClient(Options{Service: pod, AppName: "reduce-worker"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, expected := range map[string]string{
+		"foo":           "baz",
+		"homepage":      "https://app.example.com",
+		"documentation": "https://example.com/docs",
+	} {
+		if val, err := mdc.AppAnnotation(name); err != nil {
+			t.Errorf("AppAnnotation(%q): %v", name, err)
+		} else if val != expected {
+			t.Errorf("AppAnnotation(%q): expected %q, got %q", name, expected, val)
+		}
+	}
+
+	if anns, err := mdc.AppAnnotations(); err != nil {
+		t.Error("AppAnnotations:", err)
+	} else if len(anns) != 5 {
+		t.Errorf("Expected runtime annotations not repeated, got: %#v", anns)
+	}
+}
@@ -0,0 +1,127 @@
+package mdc
+
+import "github.com/appc/spec/schema/types"
+
+// Port is a port declared by an app.
+type Port struct {
+	Name            string `json:"name"`
+	Protocol        string `json:"protocol"`
+	Port            uint   `json:"port"`
+	Count           uint   `json:"count"`
+	SocketActivated bool   `json:"socketActivated"`
+	// HostPort is the host port the pod manifest exposes the port on,
+	// or 0 if it is not exposed.
+	HostPort uint `json:"hostPort,omitempty"`
+}
+
+// PortNotFoundError is returned when app does not declare the port.
+type PortNotFoundError struct {
+	Name string
+}
+
+func (err *PortNotFoundError) Error() string {
+	return "port not declared: " + err.Name
+}
+
+// Ports returns ports declared by the image manifest's app, overridden
+// and extended by the pod manifest's app, with host ports exposed by
+// the pod manifest.
+func (app *App) Ports() ([]Port, error) {
+	var ports []types.Port
+
+	ia, err := app.imageApp()
+	if err != nil {
+		return nil, err
+	}
+	if ia != nil {
+		ports = append(ports, ia.Ports...)
+	}
+
+	ra, err := app.runtimeAppOverride()
+	if err != nil {
+		return nil, err
+	}
+	if ra != nil {
+	override:
+		for _, port := range ra.Ports {
+			for i := range ports {
+				if ports[i].Name == port.Name {
+					ports[i] = port
+					continue override
+				}
+			}
+			ports = append(ports, port)
+		}
+	}
+
+	pm, err := app.mdc.PodManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	rv := make([]Port, len(ports))
+	for i, port := range ports {
+		rv[i] = Port{
+			Name:            port.Name.String(),
+			Protocol:        port.Protocol,
+			Port:            port.Port,
+			Count:           port.Count,
+			SocketActivated: port.SocketActivated,
+		}
+		for _, ep := range pm.Ports {
+			if ep.Name == port.Name {
+				rv[i].HostPort = ep.HostPort
+			}
+		}
+	}
+	return rv, nil
+}
+
+// PortInfo returns the app's port of given name, or *PortNotFoundError.
+func (app *App) PortInfo(name string) (*Port, error) {
+	ports, err := app.Ports()
+	if err != nil {
+		return nil, err
+	}
+	for i := range ports {
+		if ports[i].Name == name {
+			return &ports[i], nil
+		}
+	}
+	return nil, &PortNotFoundError{name}
+}
+
+// Port returns number of the app's port of given name.
+func (app *App) Port(name string) (uint, error) {
+	port, err := app.PortInfo(name)
+	if err != nil {
+		return 0, err
+	}
+	return port.Port, nil
+}
+
+// HostPort returns the host port on which the app's port of given name
+// is exposed, or 0 if it is not exposed.
+func (app *App) HostPort(name string) (uint, error) {
+	port, err := app.PortInfo(name)
+	if err != nil {
+		return 0, err
+	}
+	return port.HostPort, nil
+}
+
+func (mdc *MDClient) Ports() ([]Port, error) {
+	return mdc.App(mdc.ACAppName).Ports()
+}
+
+func (mdc *MDClient) PortInfo(name string) (*Port, error) {
+	return mdc.App(mdc.ACAppName).PortInfo(name)
+}
+
+func (mdc *MDClient) Port(name string) (uint, error) {
+	return mdc.App(mdc.ACAppName).Port(name)
+}
+
+func (mdc *MDClient) HostPort(name string) (uint, error) {
+	return mdc.App(mdc.ACAppName).HostPort(name)
+}
@@ -0,0 +1,73 @@
+package mdc
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/appc/spec/schema/types"
+)
+
+func TestPorts(t *testing.T) {
+	pod := newTestPod(t)
+	mdc, err := NewMDClient(Options{Service: pod, AppName: "reduce-worker"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if port, err := mdc.Port("health"); err != nil {
+		t.Error("Port:", err)
+	} else if port != 4000 {
+		t.Error("Invalid port:", port)
+	}
+
+	if _, err := mdc.Port("http"); err == nil {
+		t.Error("Expected error for undeclared port")
+	} else if _, ok := err.(*PortNotFoundError); !ok {
+		t.Errorf("Expected PortNotFoundError, got: %#v", err)
+	}
+
+	// Runtime app override and exposed ports
+	pod.Manifest.Apps[0].App = &types.App{}
+	if err := json.Unmarshal([]byte(`{
+        "exec": ["/usr/bin/reduce-worker"],
+        "user": "100",
+        "group": "300",
+        "ports": [{"name": "http", "port": 8080, "protocol": "tcp"}]
+    }`), pod.Manifest.Apps[0].App); err != nil {
+		t.Fatal(err)
+	}
+	pod.Manifest.Ports = []types.ExposedPort{{Name: "http", HostPort: 80}}
+
+	mdc, err = NewMDClient(Options{Service: pod, AppName: "reduce-worker"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ports, err := mdc.Ports(); err != nil {
+		t.Error("Ports:", err)
+	} else if expected := []Port{
+		{Name: "health", Protocol: "tcp", Port: 4000, Count: 1, SocketActivated: true},
+		{Name: "http", Protocol: "tcp", Port: 8080, Count: 1, HostPort: 80},
+	}; !reflect.DeepEqual(ports, expected) {
+		t.Errorf("Invalid ports: %#v", ports)
+	}
+
+	if port, err := mdc.HostPort("http"); err != nil {
+		t.Error("HostPort:", err)
+	} else if port != 80 {
+		t.Error("Invalid host port:", port)
+	}
+
+	if port, err := mdc.HostPort("health"); err != nil {
+		t.Error("HostPort:", err)
+	} else if port != 0 {
+		t.Error("Invalid host port:", port)
+	}
+
+	if port, err := mdc.PortInfo("health"); err != nil {
+		t.Error("PortInfo:", err)
+	} else if !port.SocketActivated || port.Protocol != "tcp" {
+		t.Errorf("Invalid port: %#v", port)
+	}
+}
@@ -0,0 +1,424 @@
+package mdc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Query is a parsed path expression selecting values from decoded
+// JSON (as returned by json.Unmarshal into interface{}). It is a
+// sequence of steps, applied to each value selected so far:
+//
+//	.name         object's field (null if the field is missing)
+//	["name"]      object's field, with any characters in the name
+//	[N]           N-th element of an array; negative N counts from
+//	              the end
+//	[]            all elements of an array (or values of an object,
+//	              ordered by key)
+//	[?PATH==VAL]  elements of an array for which value at PATH
+//	[?PATH!=VAL]  (relative to the element, like "name" or
+//	              "image.name"; "." is the element itself) is (or is
+//	              not) equal to VAL, which is a JSON string, number,
+//	              boolean, or null
+//
+// A query consisting of just "." selects the whole document.
+type Query struct {
+	expr  string
+	steps []queryStep
+}
+
+type queryStepKind int
+
+const (
+	stepField queryStepKind = iota
+	stepIndex
+	stepIterate
+	stepFilter
+)
+
+type queryStep struct {
+	kind  queryStepKind
+	field string
+	index int
+	// filter
+	path   []string
+	negate bool
+	value  interface{}
+}
+
+// QueryError is returned by ParseQuery when the expression is invalid.
+type QueryError struct {
+	Expr string
+	Pos  int
+	Msg  string
+}
+
+func (err *QueryError) Error() string {
+	return fmt.Sprintf("query %q: at position %d: %s", err.Expr, err.Pos+1, err.Msg)
+}
+
+func isQueryIdentChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+// queryParser is a hand-written recursive descent parser of query
+// expressions
+type queryParser struct {
+	expr string
+	pos  int
+}
+
+func (p *queryParser) errorf(format string, args ...interface{}) error {
+	return &QueryError{p.expr, p.pos, fmt.Sprintf(format, args...)}
+}
+
+func (p *queryParser) peek() byte {
+	if p.pos < len(p.expr) {
+		return p.expr[p.pos]
+	}
+	return 0
+}
+
+func (p *queryParser) skipSpace() {
+	for p.pos < len(p.expr) && (p.expr[p.pos] == ' ' || p.expr[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+func (p *queryParser) ident() string {
+	start := p.pos
+	for p.pos < len(p.expr) && isQueryIdentChar(p.expr[p.pos]) {
+		p.pos++
+	}
+	return p.expr[start:p.pos]
+}
+
+// literal parses a JSON scalar: string, number, true, false, or null
+func (p *queryParser) literal() (interface{}, error) {
+	start := p.pos
+	if p.peek() == '"' {
+		p.pos++
+		for p.pos < len(p.expr) && p.expr[p.pos] != '"' {
+			if p.expr[p.pos] == '\\' {
+				p.pos++
+			}
+			p.pos++
+		}
+		if p.pos >= len(p.expr) {
+			return nil, p.errorf("unterminated string")
+		}
+		p.pos++
+	} else {
+		for p.pos < len(p.expr) && (isQueryIdentChar(p.expr[p.pos]) || strings.IndexByte("+.", p.expr[p.pos]) >= 0) {
+			p.pos++
+		}
+	}
+
+	dec := json.NewDecoder(strings.NewReader(p.expr[start:p.pos]))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil || dec.More() || start == p.pos {
+		p.pos = start
+		return nil, p.errorf("invalid value")
+	}
+	return v, nil
+}
+
+// filter parses [?PATH==VALUE] after the "[?"
+func (p *queryParser) filter() (queryStep, error) {
+	step := queryStep{kind: stepFilter}
+	p.skipSpace()
+	if p.peek() == '.' {
+		p.pos++
+	}
+	for isQueryIdentChar(p.peek()) {
+		step.path = append(step.path, p.ident())
+		if p.peek() != '.' {
+			break
+		}
+		p.pos++
+	}
+	p.skipSpace()
+	switch {
+	case strings.HasPrefix(p.expr[p.pos:], "=="):
+	case strings.HasPrefix(p.expr[p.pos:], "!="):
+		step.negate = true
+	default:
+		return step, p.errorf("expected == or !=")
+	}
+	p.pos += 2
+	p.skipSpace()
+	v, err := p.literal()
+	if err != nil {
+		return step, err
+	}
+	step.value = v
+	p.skipSpace()
+	return step, nil
+}
+
+// bracket parses step in brackets after the "["
+func (p *queryParser) bracket() (queryStep, error) {
+	p.skipSpace()
+	var step queryStep
+	switch c := p.peek(); {
+	case c == ']':
+		step.kind = stepIterate
+	case c == '?':
+		p.pos++
+		var err error
+		if step, err = p.filter(); err != nil {
+			return step, err
+		}
+	case c == '"':
+		v, err := p.literal()
+		if err != nil {
+			return step, err
+		}
+		step.kind = stepField
+		step.field = v.(string)
+		p.skipSpace()
+	case c == '-' || c >= '0' && c <= '9':
+		start := p.pos
+		p.pos++
+		for c := p.peek(); c >= '0' && c <= '9'; c = p.peek() {
+			p.pos++
+		}
+		i, err := strconv.Atoi(p.expr[start:p.pos])
+		if err != nil {
+			p.pos = start
+			return step, p.errorf("invalid index")
+		}
+		step.kind = stepIndex
+		step.index = i
+		p.skipSpace()
+	default:
+		return step, p.errorf("expected index, quoted field name, ?filter, or ]")
+	}
+	if p.peek() != ']' {
+		return step, p.errorf("expected ]")
+	}
+	p.pos++
+	return step, nil
+}
+
+// ParseQuery parses query expression, or returns *QueryError.
+func ParseQuery(expr string) (*Query, error) {
+	p := &queryParser{expr: strings.TrimSpace(expr)}
+	q := &Query{expr: p.expr}
+	if p.expr == "." {
+		return q, nil
+	}
+	if c := p.peek(); c != '.' && c != '[' {
+		return nil, p.errorf("query must start with . or [")
+	}
+	for p.pos < len(p.expr) {
+		switch p.peek() {
+		case '.':
+			p.pos++
+			if p.peek() == '[' {
+				continue
+			}
+			name := p.ident()
+			if name == "" {
+				return nil, p.errorf("expected field name")
+			}
+			q.steps = append(q.steps, queryStep{kind: stepField, field: name})
+		case '[':
+			p.pos++
+			step, err := p.bracket()
+			if err != nil {
+				return nil, err
+			}
+			q.steps = append(q.steps, step)
+		default:
+			return nil, p.errorf("unexpected %q", p.peek())
+		}
+	}
+	return q, nil
+}
+
+func (q *Query) String() string {
+	return q.expr
+}
+
+// queryNumber returns numeric value of decoded JSON number
+func queryNumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// queryEqual compares decoded JSON values; numbers are compared by
+// value
+func queryEqual(a, b interface{}) bool {
+	if af, ok := queryNumber(a); ok {
+		bf, ok := queryNumber(b)
+		return ok && af == bf
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// queryField returns object's field; null if obj is null or the field
+// is missing
+func queryField(obj interface{}, name string) (interface{}, error) {
+	switch o := obj.(type) {
+	case map[string]interface{}:
+		return o[name], nil
+	case nil:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("cannot get field %q of %s", name, queryTypeName(obj))
+}
+
+func queryTypeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	}
+	return "number"
+}
+
+func (step queryStep) apply(v interface{}) ([]interface{}, error) {
+	switch step.kind {
+	case stepField:
+		f, err := queryField(v, step.field)
+		if err != nil {
+			return nil, err
+		}
+		return []interface{}{f}, nil
+	case stepIndex:
+		arr, ok := v.([]interface{})
+		if !ok {
+			if v == nil {
+				return []interface{}{nil}, nil
+			}
+			return nil, fmt.Errorf("cannot index %s", queryTypeName(v))
+		}
+		i := step.index
+		if i < 0 {
+			i += len(arr)
+		}
+		if i < 0 || i >= len(arr) {
+			return []interface{}{nil}, nil
+		}
+		return []interface{}{arr[i]}, nil
+	case stepIterate:
+		switch o := v.(type) {
+		case []interface{}:
+			return o, nil
+		case map[string]interface{}:
+			keys := make([]string, 0, len(o))
+			for k := range o {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			rv := make([]interface{}, len(keys))
+			for i, k := range keys {
+				rv[i] = o[k]
+			}
+			return rv, nil
+		case nil:
+			return nil, nil
+		}
+		return nil, fmt.Errorf("cannot iterate over %s", queryTypeName(v))
+	case stepFilter:
+		arr, ok := v.([]interface{})
+		if !ok {
+			if v == nil {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("cannot filter %s", queryTypeName(v))
+		}
+		var rv []interface{}
+		for _, elt := range arr {
+			fv := elt
+			for _, name := range step.path {
+				var err error
+				if fv, err = queryField(fv, name); err != nil {
+					return nil, err
+				}
+			}
+			if queryEqual(fv, step.value) != step.negate {
+				rv = append(rv, elt)
+			}
+		}
+		return rv, nil
+	}
+	panic("CAN'T HAPPEN")
+}
+
+// Run applies query to decoded JSON data, and returns selected values.
+func (q *Query) Run(data interface{}) ([]interface{}, error) {
+	values := []interface{}{data}
+	for _, step := range q.steps {
+		var next []interface{}
+		for _, v := range values {
+			selected, err := step.apply(v)
+			if err != nil {
+				return nil, fmt.Errorf("query %q: %v", q.expr, err)
+			}
+			next = append(next, selected...)
+		}
+		values = next
+	}
+	return values, nil
+}
+
+// queryManifest runs query expression on manifest's JSON
+// representation, decoded with numbers kept as json.Number
+func queryManifest(manifest interface{}, expr string) ([]interface{}, error) {
+	q, err := ParseQuery(expr)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.UseNumber()
+	var data interface{}
+	if err := dec.Decode(&data); err != nil {
+		return nil, err
+	}
+	return q.Run(data)
+}
+
+// QueryPodManifest runs query expression (see Query) on the parsed
+// pod manifest.
+func (mdc *MDClient) QueryPodManifest(expr string) ([]interface{}, error) {
+	pm, err := mdc.PodManifest()
+	if err != nil {
+		return nil, err
+	}
+	return queryManifest(pm, expr)
+}
+
+// QueryAppImageManifest runs query expression (see Query) on the
+// parsed image manifest of current app.
+func (mdc *MDClient) QueryAppImageManifest(expr string) ([]interface{}, error) {
+	im, err := mdc.AppImageManifest()
+	if err != nil {
+		return nil, err
+	}
+	return queryManifest(im, expr)
+}
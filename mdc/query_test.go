@@ -0,0 +1,108 @@
+package mdc
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseQueryErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"apps",
+		".apps[",
+		".apps[x]",
+		".apps[?name]",
+		".apps[?name==]",
+		`.apps[?name=="x]`,
+		".apps..name",
+		".apps[0]name",
+	} {
+		if _, err := ParseQuery(expr); err == nil {
+			t.Errorf("ParseQuery(%q): expected error", expr)
+		} else if _, ok := err.(*QueryError); !ok {
+			t.Errorf("ParseQuery(%q): expected QueryError, got: %#v", expr, err)
+		}
+	}
+}
+
+func TestQuery(t *testing.T) {
+	var data interface{}
+	if err := json.Unmarshal([]byte(`{
+        "apps": [
+            {"name": "web", "image": {"name": "example.com/web"}, "ports": [{"name": "http", "port": 80}]},
+            {"name": "db", "image": {"name": "example.com/db"}, "ports": [{"name": "pg", "port": 5432}]}
+        ],
+        "annotations": [{"name": "postgresql/host", "value": "db.example.com"}],
+        "labels": {"os": "linux", "arch": "amd64"},
+        "tags": ["a", "b", "c"]
+    }`), &data); err != nil {
+		t.Fatal(err)
+	}
+
+	for expr, expected := range map[string][]interface{}{
+		".apps[].image.name":                           {"example.com/web", "example.com/db"},
+		`.apps[?name=="db"].ports[0].port`:             {5432.0},
+		`.apps[?name!="db"].name`:                      {"web"},
+		`.apps[].ports[?port==80].name`:                {"http"},
+		`.apps[?image.name=="example.com/web"].name`:   {"web"},
+		`.annotations[?name=="postgresql/host"].value`: {"db.example.com"},
+		`.apps[?name=="nope"].name`:                    nil,
+		`.labels["arch"]`:                              {"amd64"},
+		`.labels[]`:                                    {"amd64", "linux"},
+		`.labels.missing`:                              {nil},
+		`.tags[-1]`:                                    {"c"},
+		`.tags[5]`:                                     {nil},
+		`.tags[?.=="b"]`:                               {"b"},
+		`.["tags"][1]`:                                 {"b"},
+		`.missing[].name`:                              nil,
+	} {
+		q, err := ParseQuery(expr)
+		if err != nil {
+			t.Errorf("ParseQuery(%q): %v", expr, err)
+			continue
+		}
+		if values, err := q.Run(data); err != nil {
+			t.Errorf("%s: %v", expr, err)
+		} else if !reflect.DeepEqual(values, expected) {
+			t.Errorf("%s: expected %#v, got %#v", expr, expected, values)
+		}
+	}
+
+	if values, err := mustQuery(t, ".").Run(data); err != nil || len(values) != 1 || !reflect.DeepEqual(values[0], data) {
+		t.Errorf(".: %#v %v", values, err)
+	}
+
+	for _, expr := range []string{".apps.name", ".tags[0].name", ".labels[0]"} {
+		if _, err := mustQuery(t, expr).Run(data); err == nil {
+			t.Errorf("%s: expected error", expr)
+		}
+	}
+}
+
+func mustQuery(t *testing.T, expr string) *Query {
+	q, err := ParseQuery(expr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return q
+}
+
+func TestQueryManifests(t *testing.T) {
+	mdc, err := NewMDClient(Options{Service: newTestPod(t), AppName: "reduce-worker"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if values, err := mdc.QueryPodManifest(".apps[].name"); err != nil {
+		t.Error("QueryPodManifest:", err)
+	} else if !reflect.DeepEqual(values, []interface{}{"reduce-worker", "backup"}) {
+		t.Errorf("Invalid result: %#v", values)
+	}
+
+	if values, err := mdc.QueryAppImageManifest(`.app.ports[?name=="health"].port`); err != nil {
+		t.Error("QueryAppImageManifest:", err)
+	} else if !reflect.DeepEqual(values, []interface{}{json.Number("4000")}) {
+		t.Errorf("Invalid result: %#v", values)
+	}
+}
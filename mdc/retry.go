@@ -0,0 +1,88 @@
+package mdc
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"time"
+)
+
+// RetryPolicy controls timeouts and retries of metadata service
+// requests. Requests that fail with a connection error or a 5xx
+// response are retried with exponential backoff until Deadline
+// passes.
+type RetryPolicy struct {
+	// Deadline limits retries of a single operation: it is counted
+	// from the client's first request after it was created, or after
+	// ResetDeadline or Flush was called, and failed requests are not
+	// retried after it passes. Failed requests are not retried if zero.
+	Deadline time.Duration
+	// Timeout limits a single HTTP request. No limit if zero.
+	Timeout time.Duration
+	// Backoff is the delay before first retry. It is doubled after
+	// each attempt, up to MaxBackoff. Actual delay is randomly chosen
+	// between half and full backoff.
+	Backoff, MaxBackoff time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	Deadline:   10 * time.Second,
+	Timeout:    2 * time.Second,
+	Backoff:    100 * time.Millisecond,
+	MaxBackoff: 2 * time.Second,
+}
+
+// RetryPolicyFromEnv returns DefaultRetryPolicy with fields
+// overridden by MDC_DEADLINE, MDC_TIMEOUT, MDC_BACKOFF, and
+// MDC_MAX_BACKOFF environment variables, if set.
+func RetryPolicyFromEnv() (RetryPolicy, error) {
+	rp := DefaultRetryPolicy
+	for _, ev := range []struct {
+		name string
+		dst  *time.Duration
+	}{
+		{"MDC_DEADLINE", &rp.Deadline},
+		{"MDC_TIMEOUT", &rp.Timeout},
+		{"MDC_BACKOFF", &rp.Backoff},
+		{"MDC_MAX_BACKOFF", &rp.MaxBackoff},
+	} {
+		if val := os.Getenv(ev.name); val != "" {
+			d, err := time.ParseDuration(val)
+			if err != nil {
+				return rp, fmt.Errorf("%s: %v", ev.name, err)
+			}
+			*ev.dst = d
+		}
+	}
+	return rp, nil
+}
+
+func (rp RetryPolicy) String() string {
+	if rp.Deadline <= 0 {
+		return fmt.Sprintf("no retries, timeout=%v", rp.Timeout)
+	}
+	return fmt.Sprintf("deadline=%v timeout=%v backoff=%v..%v", rp.Deadline, rp.Timeout, rp.Backoff, rp.MaxBackoff)
+}
+
+// delay returns jittered delay for backoff
+func (rp RetryPolicy) delay(backoff time.Duration) time.Duration {
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
+// next returns backoff for next attempt
+func (rp RetryPolicy) next(backoff time.Duration) time.Duration {
+	backoff *= 2
+	if rp.MaxBackoff > 0 && backoff > rp.MaxBackoff {
+		backoff = rp.MaxBackoff
+	}
+	return backoff
+}
+
+// isRetryable returns true for connection errors and server errors
+func isRetryable(err error) bool {
+	if serr, ok := err.(*StatusError); ok {
+		return serr.StatusCode >= http.StatusInternalServerError
+	}
+	return true
+}
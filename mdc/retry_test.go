@@ -0,0 +1,176 @@
+package mdc
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+var testRetryPolicy = RetryPolicy{
+	Deadline:   time.Second,
+	Timeout:    100 * time.Millisecond,
+	Backoff:    time.Millisecond,
+	MaxBackoff: 10 * time.Millisecond,
+}
+
+// flakyServer serves metadata after failing first n requests
+func flakyServer(n int, status int) (*httptest.Server, *int) {
+	requests := 0
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests <= n {
+			w.WriteHeader(status)
+			return
+		}
+		serveMetadata(w, r)
+	})), &requests
+}
+
+func TestRetry(t *testing.T) {
+	srv, requests := flakyServer(3, http.StatusServiceUnavailable)
+	defer srv.Close()
+
+	var logged []string
+	mdc, err := NewMDClient(Options{
+		MetadataURL: srv.URL,
+		AppName:     "reduce-worker",
+		Retry:       testRetryPolicy,
+		Logf: func(format string, v ...interface{}) {
+			logged = append(logged, fmt.Sprintf(format, v...))
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if uuid, err := mdc.UUID(); err != nil {
+		t.Error("UUID:", err)
+	} else if uuid != pod_uuid {
+		t.Error("Invalid UUID:", uuid)
+	}
+
+	if *requests != 4 {
+		t.Error("Expected 4 requests, got", *requests)
+	}
+
+	if len(logged) != 3 {
+		t.Error("Expected 3 retries logged, got", logged)
+	}
+}
+
+func TestNoRetry(t *testing.T) {
+	srv, requests := flakyServer(1, http.StatusBadRequest)
+	defer srv.Close()
+
+	mdc, err := NewMDClient(Options{MetadataURL: srv.URL, AppName: "reduce-worker", Retry: testRetryPolicy})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := mdc.UUID(); err == nil {
+		t.Error("Bad request didn't fail")
+	}
+
+	if *requests != 1 {
+		t.Error("Expected single request, got", *requests)
+	}
+}
+
+func TestRetryDeadline(t *testing.T) {
+	srv, _ := flakyServer(0, 0)
+	srv.Close()
+
+	rp := testRetryPolicy
+	rp.Deadline = 50 * time.Millisecond
+	mdc, err := NewMDClient(Options{MetadataURL: srv.URL, AppName: "reduce-worker", Retry: rp})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	if _, err := mdc.UUID(); err == nil {
+		t.Error("Request to closed server didn't fail")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Error("Deadline not respected, elapsed:", elapsed)
+	}
+}
+
+func TestRetryPolicyFromEnv(t *testing.T) {
+	defer os.Unsetenv("MDC_DEADLINE")
+	defer os.Unsetenv("MDC_BACKOFF")
+
+	os.Setenv("MDC_DEADLINE", "1m")
+	os.Setenv("MDC_BACKOFF", "1s")
+	if rp, err := RetryPolicyFromEnv(); err != nil {
+		t.Error("RetryPolicyFromEnv:", err)
+	} else if rp.Deadline != time.Minute || rp.Backoff != time.Second || rp.Timeout != DefaultRetryPolicy.Timeout {
+		t.Error("Invalid retry policy:", rp)
+	}
+
+	os.Setenv("MDC_DEADLINE", "forever")
+	if _, err := RetryPolicyFromEnv(); err == nil {
+		t.Error("Invalid MDC_DEADLINE didn't fail")
+	}
+}
+
+
+func TestRetryDeadlinePerOperation(t *testing.T) {
+	requests, failures := 0, 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		serveMetadata(w, r)
+	}))
+	defer srv.Close()
+
+	rp := testRetryPolicy
+	rp.Deadline = 50 * time.Millisecond
+	mdc, err := NewMDClient(Options{MetadataURL: srv.URL, AppName: "reduce-worker", Retry: rp})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The deadline is shared by all requests of an operation
+	failures = 1000
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if _, err := mdc.PodManifest(); err == nil {
+			t.Error("Request to failing server didn't fail")
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Error("Deadline restarted for each request, elapsed:", elapsed)
+	}
+
+	// ResetDeadline starts a new one, keeping cached metadata
+	failures = 0
+	mdc.ResetDeadline()
+	if _, err := mdc.UUID(); err != nil {
+		t.Fatal("UUID:", err)
+	}
+	mdc.ResetDeadline()
+	failures = 2
+	if _, err := mdc.PodManifest(); err != nil {
+		t.Error("PodManifest after ResetDeadline:", err)
+	}
+	before := requests
+	if uuid, err := mdc.UUID(); err != nil || uuid != pod_uuid {
+		t.Error("UUID:", uuid, err)
+	}
+	if requests != before {
+		t.Error("ResetDeadline dropped cached metadata")
+	}
+
+	mdc.Flush()
+	if !mdc.started.IsZero() {
+		t.Error("Flush didn't restart the deadline")
+	}
+}
@@ -0,0 +1,39 @@
+package mdc
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ServeHTTP serves the pod's metadata as the App Container Metadata
+// Service would, under the /acMetadata/v1/ path. Requests without the
+// "Metadata-Flavor: AppContainer" header are rejected.
+func (p *Pod) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if hdr, ok := r.Header["Metadata-Flavor"]; !ok || len(hdr) != 1 || hdr[0] != "AppContainer" {
+		http.Error(w, "Metadata-Flavor header missing or invalid", http.StatusBadRequest)
+		return
+	}
+
+	if !strings.HasPrefix(r.URL.Path, "/acMetadata/v1/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	var form url.Values
+	if r.Method == "POST" {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		form = r.PostForm
+	}
+
+	status, body, err := p.Request(r.Method, strings.TrimPrefix(r.URL.Path, "/acMetadata/v1/"), form)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(status)
+	w.Write(body)
+}
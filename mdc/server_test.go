@@ -0,0 +1,70 @@
+package mdc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServeHTTP(t *testing.T) {
+	srv := httptest.NewServer(newTestPod(t))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/acMetadata/v1/pod/uuid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Error("Request without Metadata-Flavor header not rejected:", resp.Status)
+	}
+
+	for _, appName := range []string{"reduce-worker", "backup"} {
+		mdc, err := NewMDClient(Options{MetadataURL: srv.URL, AppName: appName})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if uuid, err := mdc.UUID(); err != nil {
+			t.Error("UUID:", err)
+		} else if uuid != pod_uuid {
+			t.Error("Invalid UUID:", uuid)
+		}
+
+		if val, err := mdc.PodAnnotation("ip-address"); err != nil {
+			t.Error("PodAnnotation:", err)
+		} else if val != "10.1.2.3" {
+			t.Error("Invalid annotation value:", val)
+		}
+
+		if _, err := mdc.AppImageID(); err != nil {
+			t.Error("AppImageID:", err)
+		}
+
+		sig, err := mdc.Sign("some content")
+		if err != nil {
+			t.Fatal("Sign:", err)
+		}
+		if ok, err := mdc.Verify("some content", sig, pod_uuid); err != nil {
+			t.Error("Verify:", err)
+		} else if !ok {
+			t.Error("Valid signature not verified")
+		}
+		if ok, err := mdc.Verify("some content", sig+"x", pod_uuid); err != nil {
+			t.Error("Verify:", err)
+		} else if ok {
+			t.Error("Invalid signature verified")
+		}
+	}
+
+	mdc, err := NewMDClient(Options{MetadataURL: srv.URL, AppName: "nonexistent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mdc.AppAnnotations(); !IsNotFound(err) {
+		t.Error("Expected not found error, got:", err)
+	}
+	if _, err := mdc.Get("pod/hmac/sign"); err == nil {
+		t.Error("GET of pod/hmac/sign didn't fail")
+	}
+}
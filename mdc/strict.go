@@ -0,0 +1,69 @@
+package mdc
+
+import "strings"
+
+// MissingAnnotationsError is returned by Strict when annotations
+// accessed by the function don't exist.
+type MissingAnnotationsError struct {
+	// Missing lists missing annotations, e.g. "pod annotation
+	// postgresql/host" or "app backup annotation role"; annotations
+	// looked up in all layers are listed as "annotation NAME".
+	Missing []string
+	// Err is the error returned by the function, if any; it may be
+	// a consequence of the missing annotations.
+	Err error
+}
+
+func (err *MissingAnnotationsError) Error() string {
+	msg := "missing " + strings.Join(err.Missing, ", ")
+	if err.Err != nil {
+		msg += "; also: " + err.Err.Error()
+	}
+	return msg
+}
+
+// Strict calls f (typically, a template execution) in strict mode:
+// missing annotations don't stop f, but are recorded, and reported
+// all at once as *MissingAnnotationsError after f returns. Accessors
+// that would return an empty string or *AnnotationNotFoundError for
+// a missing annotation return a zero value and no error instead;
+// accessors with a default value (like PodAnnotationOr) and Has…
+// accessors are not affected.
+func (mdc *MDClient) Strict(f func() error) error {
+	mdc.strict, mdc.missingAnnotations = true, nil
+	defer func() { mdc.strict, mdc.missingAnnotations = false, nil }()
+
+	err := f()
+	if len(mdc.missingAnnotations) > 0 {
+		return &MissingAnnotationsError{mdc.missingAnnotations, err}
+	}
+	return err
+}
+
+// missing records annotation name missing from source desc ("pod",
+// "app NAME", or "" for layered lookup) in strict mode
+func (mdc *MDClient) missing(desc, name string) {
+	if !mdc.strict {
+		return
+	}
+	if desc != "" {
+		desc += " "
+	}
+	desc += "annotation " + name
+	for _, m := range mdc.missingAnnotations {
+		if m == desc {
+			return
+		}
+	}
+	mdc.missingAnnotations = append(mdc.missingAnnotations, desc)
+}
+
+// notFound returns *AnnotationNotFoundError for a required annotation,
+// or in strict mode records it and returns nil
+func (mdc *MDClient) notFound(desc, name string) error {
+	if !mdc.strict {
+		return &AnnotationNotFoundError{name}
+	}
+	mdc.missing(desc, name)
+	return nil
+}
@@ -0,0 +1,57 @@
+package mdc
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestStrict(t *testing.T) {
+	mdc, err := NewMDClient(Options{Service: newTestPod(t), AppName: "reduce-worker"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl, err := NewTemplate("strict").Parse(`
+{{.PodAnnotation "ip-address"}}
+{{.PodAnnotation "postgresql/user"}}
+{{.MustPodAnnotation "postgresql/password"}}
+{{.PodAnnotation "postgresql/user"}}
+{{.PodAnnotationOr "postgresql/db" "app"}}
+{{.HasPodAnnotation "postgresql/ssl"}}
+{{.AppAnnotation "role"}}
+{{(.App "backup").MustAnnotation "schedule"}}
+{{.PodAnnotationInt "workers"}}
+{{.MustAnnotation "homepage"}}
+{{.Annotation "region"}}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &bytes.Buffer{}
+	err = mdc.Strict(func() error { return tmpl.Execute(buf, mdc) })
+	if merr, ok := err.(*MissingAnnotationsError); !ok {
+		t.Fatalf("Expected MissingAnnotationsError, got: %#v", err)
+	} else if expected := []string{
+		"pod annotation postgresql/user",
+		"pod annotation postgresql/password",
+		"app reduce-worker annotation role",
+		"app backup annotation schedule",
+		"pod annotation workers",
+		"annotation region",
+	}; !reflect.DeepEqual(merr.Missing, expected) {
+		t.Errorf("Missing annotations: %#v\nexpected: %#v", merr.Missing, expected)
+	} else if merr.Err != nil {
+		t.Error("Unexpected template error:", merr.Err)
+	}
+
+	if err := mdc.Strict(func() error { return nil }); err != nil {
+		t.Error("Strict:", err)
+	}
+
+	// Strict mode ends with Strict call
+	if _, err := mdc.MustPodAnnotation("postgresql/password"); err == nil {
+		t.Error("Expected error for missing annotation outside strict mode")
+	}
+}
@@ -0,0 +1,63 @@
+package mdc
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"text/template"
+)
+
+// maxIncludeDepth limits nesting of include calls, so that a template
+// including itself fails instead of exhausting the stack
+const maxIncludeDepth = 100
+
+// NewTemplate returns new template with FuncMap functions and the
+// include function. Include executes a template defined in the same
+// set; if there is no such template, NAME is looked up as a file path
+// relative to searchPath directories (in order), which is parsed into
+// the set under that name.
+func NewTemplate(name string, searchPath ...string) *template.Template {
+	tmpl := template.New(name).Funcs(FuncMap())
+	depth := 0
+	return tmpl.Funcs(template.FuncMap{
+		"include": func(name string, data interface{}) (string, error) {
+			t := tmpl.Lookup(name)
+			if t == nil {
+				var err error
+				if t, err = loadTemplate(tmpl, name, searchPath); err != nil {
+					return "", err
+				}
+			}
+
+			if depth >= maxIncludeDepth {
+				return "", fmt.Errorf("include %s: nested too deeply", name)
+			}
+			depth++
+			defer func() { depth-- }()
+
+			buf := &bytes.Buffer{}
+			if err := t.Execute(buf, data); err != nil {
+				return "", err
+			}
+			return buf.String(), nil
+		},
+	})
+}
+
+// loadTemplate finds file name in searchPath, and parses it into tmpl's
+// set as template name
+func loadTemplate(tmpl *template.Template, name string, searchPath []string) (*template.Template, error) {
+	for _, dir := range searchPath {
+		data, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return tmpl.New(name).Parse(string(data))
+	}
+	return nil, fmt.Errorf("include %s: template not defined and not found in search path %q", name, searchPath)
+}
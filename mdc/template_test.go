@@ -0,0 +1,55 @@
+package mdc
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestInclude(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mdc-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "item.tmpl"), []byte(`- {{.}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl, err := NewTemplate("main", dir).Parse(
+		`{{define "list"}}{{range .}}{{include "item.tmpl" .}}` + "\n" + `{{end}}{{end}}items:{{include "list" . | nindent 2}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, []string{"a", "b"}); err != nil {
+		t.Error("Execute:", err)
+	} else if expected := "items:\n  - a\n  - b\n  "; buf.String() != expected {
+		t.Errorf("got %#v, expected %#v", buf.String(), expected)
+	}
+
+	for _, text := range []string{
+		`{{include "nonexistent.tmpl" .}}`,
+		`{{define "loop"}}{{include "loop" .}}{{end}}{{include "loop" .}}`,
+	} {
+		tmpl, err := NewTemplate("main", dir).Parse(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := tmpl.Execute(ioutil.Discard, nil); err == nil {
+			t.Errorf("%s: expected error", text)
+		}
+	}
+
+	tmpl, err = template.New("main").Funcs(FuncMap()).Parse(`{{include "main" .}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tmpl.Execute(ioutil.Discard, nil); err == nil || !strings.Contains(err.Error(), "NewTemplate") {
+		t.Error("Expected include to be unavailable with plain FuncMap, got:", err)
+	}
+}
@@ -0,0 +1,199 @@
+package mdc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/kubernetes/pkg/api/resource"
+)
+
+// AnnotationValueError is returned by typed annotation accessors when
+// annotation's value (or the default) can't be parsed.
+type AnnotationValueError struct {
+	Name, Value, Type string
+}
+
+func (err *AnnotationValueError) Error() string {
+	return fmt.Sprintf("annotation %s: invalid %s value: %q", err.Name, err.Type, err.Value)
+}
+
+// typedValue returns value of the annotation, or the default
+// (formatted with fmt.Sprint) if annotation is not found. If there's
+// no default, *AnnotationNotFoundError is returned; in strict mode,
+// ok is false and error is nil, and the accessor returns zero value.
+func (src annotationSource) typedValue(name string, def []interface{}) (v string, ok bool, err error) {
+	v, found, err := src.value(name)
+	if err != nil || found {
+		return v, found, err
+	}
+	if len(def) > 0 {
+		return fmt.Sprint(def[0]), true, nil
+	}
+	return "", false, src.mdc.notFound(src.desc, name)
+}
+
+func (src annotationSource) intValue(name string, def []interface{}) (int64, error) {
+	v, ok, err := src.typedValue(name, def)
+	if !ok || err != nil {
+		return 0, err
+	}
+	i, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+	if err != nil {
+		return 0, &AnnotationValueError{name, v, "integer"}
+	}
+	return i, nil
+}
+
+func (src annotationSource) floatValue(name string, def []interface{}) (float64, error) {
+	v, ok, err := src.typedValue(name, def)
+	if !ok || err != nil {
+		return 0, err
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+	if err != nil {
+		return 0, &AnnotationValueError{name, v, "number"}
+	}
+	return f, nil
+}
+
+func (src annotationSource) boolValue(name string, def []interface{}) (bool, error) {
+	v, ok, err := src.typedValue(name, def)
+	if !ok || err != nil {
+		return false, err
+	}
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "1", "t", "true", "y", "yes", "on":
+		return true, nil
+	case "0", "f", "false", "n", "no", "off":
+		return false, nil
+	}
+	return false, &AnnotationValueError{name, v, "boolean"}
+}
+
+func (src annotationSource) durationValue(name string, def []interface{}) (time.Duration, error) {
+	v, ok, err := src.typedValue(name, def)
+	if !ok || err != nil {
+		return 0, err
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(v))
+	if err != nil {
+		return 0, &AnnotationValueError{name, v, "duration"}
+	}
+	return d, nil
+}
+
+func (src annotationSource) bytesValue(name string, def []interface{}) (int64, error) {
+	v, ok, err := src.typedValue(name, def)
+	if !ok || err != nil {
+		return 0, err
+	}
+	q, err := resource.ParseQuantity(strings.TrimSpace(v))
+	if err != nil || q.Value() < 0 {
+		return 0, &AnnotationValueError{name, v, "byte size"}
+	}
+	return q.Value(), nil
+}
+
+func (src annotationSource) listValue(name string, def []interface{}) ([]string, error) {
+	v, ok, err := src.typedValue(name, def)
+	if !ok || err != nil {
+		return nil, err
+	}
+	rv := []string{}
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			rv = append(rv, item)
+		}
+	}
+	return rv, nil
+}
+
+// PodAnnotationInt returns pod's annotation value as an integer. If
+// the annotation does not exist, the optional default is used;
+// without a default, an error is returned. Defaults of all typed
+// accessors may be given either as a value of the right type or as a
+// string, and are validated the same way as annotation values.
+func (mdc *MDClient) PodAnnotationInt(name string, def ...interface{}) (int64, error) {
+	return mdc.podSource().intValue(name, def)
+}
+
+// PodAnnotationFloat returns pod's annotation value as a number.
+func (mdc *MDClient) PodAnnotationFloat(name string, def ...interface{}) (float64, error) {
+	return mdc.podSource().floatValue(name, def)
+}
+
+// PodAnnotationBool returns pod's annotation value as a boolean;
+// true/false, yes/no, on/off, and 1/0 are accepted.
+func (mdc *MDClient) PodAnnotationBool(name string, def ...interface{}) (bool, error) {
+	return mdc.podSource().boolValue(name, def)
+}
+
+// PodAnnotationDuration returns pod's annotation value parsed as
+// a Go duration (e.g. "1h30m").
+func (mdc *MDClient) PodAnnotationDuration(name string, def ...interface{}) (time.Duration, error) {
+	return mdc.podSource().durationValue(name, def)
+}
+
+// PodAnnotationBytes returns pod's annotation value parsed as a byte
+// size, with decimal (k, M, G, T, P, E) or binary (Ki, Mi, Gi, Ti,
+// Pi, Ei) suffix, like memory limits are.
+func (mdc *MDClient) PodAnnotationBytes(name string, def ...interface{}) (int64, error) {
+	return mdc.podSource().bytesValue(name, def)
+}
+
+// PodAnnotationList returns pod's annotation value as a list of
+// comma-separated items, with whitespace trimmed and empty items
+// skipped.
+func (mdc *MDClient) PodAnnotationList(name string, def ...interface{}) ([]string, error) {
+	return mdc.podSource().listValue(name, def)
+}
+
+func (mdc *MDClient) AppAnnotationInt(name string, def ...interface{}) (int64, error) {
+	return mdc.App(mdc.ACAppName).AnnotationInt(name, def...)
+}
+
+func (mdc *MDClient) AppAnnotationFloat(name string, def ...interface{}) (float64, error) {
+	return mdc.App(mdc.ACAppName).AnnotationFloat(name, def...)
+}
+
+func (mdc *MDClient) AppAnnotationBool(name string, def ...interface{}) (bool, error) {
+	return mdc.App(mdc.ACAppName).AnnotationBool(name, def...)
+}
+
+func (mdc *MDClient) AppAnnotationDuration(name string, def ...interface{}) (time.Duration, error) {
+	return mdc.App(mdc.ACAppName).AnnotationDuration(name, def...)
+}
+
+func (mdc *MDClient) AppAnnotationBytes(name string, def ...interface{}) (int64, error) {
+	return mdc.App(mdc.ACAppName).AnnotationBytes(name, def...)
+}
+
+func (mdc *MDClient) AppAnnotationList(name string, def ...interface{}) ([]string, error) {
+	return mdc.App(mdc.ACAppName).AnnotationList(name, def...)
+}
+
+func (app *App) AnnotationInt(name string, def ...interface{}) (int64, error) {
+	return app.source().intValue(name, def)
+}
+
+func (app *App) AnnotationFloat(name string, def ...interface{}) (float64, error) {
+	return app.source().floatValue(name, def)
+}
+
+func (app *App) AnnotationBool(name string, def ...interface{}) (bool, error) {
+	return app.source().boolValue(name, def)
+}
+
+func (app *App) AnnotationDuration(name string, def ...interface{}) (time.Duration, error) {
+	return app.source().durationValue(name, def)
+}
+
+func (app *App) AnnotationBytes(name string, def ...interface{}) (int64, error) {
+	return app.source().bytesValue(name, def)
+}
+
+func (app *App) AnnotationList(name string, def ...interface{}) ([]string, error) {
+	return app.source().listValue(name, def)
+}
@@ -0,0 +1,106 @@
+package mdc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTypedAnnotations(t *testing.T) {
+	mdc := newTestClient(t)
+
+	if port, err := mdc.PodAnnotationInt("postgresql/port"); err != nil {
+		t.Error("PodAnnotationInt:", err)
+	} else if port != 5432 {
+		t.Error("Invalid port:", port)
+	}
+
+	if workers, err := mdc.PodAnnotationInt("workers", 4); err != nil {
+		t.Error("PodAnnotationInt:", err)
+	} else if workers != 4 {
+		t.Error("Invalid default:", workers)
+	}
+
+	if _, err := mdc.PodAnnotationInt("workers"); err == nil {
+		t.Error("Expected error for missing annotation")
+	} else if _, ok := err.(*AnnotationNotFoundError); !ok {
+		t.Errorf("Expected AnnotationNotFoundError, got: %#v", err)
+	}
+
+	if _, err := mdc.PodAnnotationInt("postgresql/host"); err == nil {
+		t.Error("Expected error for invalid integer")
+	} else if verr, ok := err.(*AnnotationValueError); !ok {
+		t.Errorf("Expected AnnotationValueError, got: %#v", err)
+	} else if verr.Name != "postgresql/host" || verr.Value != "db.example.com" {
+		t.Errorf("Invalid error: %#v", verr)
+	} else if msg := err.Error(); !strings.Contains(msg, "postgresql/host") || !strings.Contains(msg, "db.example.com") {
+		t.Error("Error message should name annotation and value:", msg)
+	}
+
+	if _, err := mdc.PodAnnotationInt("workers", "many"); err == nil {
+		t.Error("Expected invalid default to be rejected")
+	}
+
+	if f, err := mdc.PodAnnotationFloat("ratio", "0.75"); err != nil {
+		t.Error("PodAnnotationFloat:", err)
+	} else if f != 0.75 {
+		t.Error("Invalid float:", f)
+	}
+
+	for _, c := range []struct {
+		def   interface{}
+		value bool
+	}{{true, true}, {"yes", true}, {"ON", true}, {"0", false}, {"off", false}, {false, false}} {
+		if b, err := mdc.PodAnnotationBool("debug", c.def); err != nil {
+			t.Error("PodAnnotationBool:", err)
+		} else if b != c.value {
+			t.Errorf("PodAnnotationBool(%v) = %v", c.def, b)
+		}
+	}
+	if _, err := mdc.PodAnnotationBool("debug", "maybe"); err == nil {
+		t.Error("Expected error for invalid boolean")
+	}
+
+	if d, err := mdc.PodAnnotationDuration("timeout", 90*time.Second); err != nil {
+		t.Error("PodAnnotationDuration:", err)
+	} else if d != 90*time.Second {
+		t.Error("Invalid duration:", d)
+	}
+	if _, err := mdc.PodAnnotationDuration("timeout", "90"); err == nil {
+		t.Error("Expected error for duration without unit")
+	}
+
+	for def, value := range map[string]int64{"512Mi": 512 << 20, "1G": 1000000000, "4096": 4096} {
+		if b, err := mdc.PodAnnotationBytes("memory", def); err != nil {
+			t.Error("PodAnnotationBytes:", err)
+		} else if b != value {
+			t.Errorf("PodAnnotationBytes(%v) = %v", def, b)
+		}
+	}
+	if _, err := mdc.PodAnnotationBytes("memory", "lots"); err == nil {
+		t.Error("Expected error for invalid byte size")
+	}
+
+	if l, err := mdc.PodAnnotationList("hosts", " a, b,,c "); err != nil {
+		t.Error("PodAnnotationList:", err)
+	} else if !reflect.DeepEqual(l, []string{"a", "b", "c"}) {
+		t.Errorf("Invalid list: %#v", l)
+	}
+
+	if l, err := mdc.AppAnnotationList("authors"); err != nil {
+		t.Error("AppAnnotationList:", err)
+	} else if len(l) != 2 || l[0] != "Carly Container <carly@example.com>" {
+		t.Errorf("Invalid list: %#v", l)
+	}
+
+	if _, err := mdc.AppAnnotationInt("foo"); err == nil {
+		t.Error("Expected error for invalid integer")
+	}
+
+	if i, err := mdc.App("reduce-worker").AnnotationInt("replicas", 3); err != nil {
+		t.Error("AnnotationInt:", err)
+	} else if i != 3 {
+		t.Error("Invalid default:", i)
+	}
+}
@@ -0,0 +1,116 @@
+package mdc
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/appc/spec/schema/types"
+)
+
+// WaitError is returned by Wait when metadata is not available before
+// the timeout.
+type WaitError struct {
+	Timeout time.Duration
+	// Err is the last error returned by the metadata service, if any.
+	Err error
+	// MissingPodAnnotations and MissingAppAnnotations list annotations
+	// that were still missing when wait timed out.
+	MissingPodAnnotations, MissingAppAnnotations []string
+}
+
+func (err *WaitError) Error() string {
+	msg := fmt.Sprintf("metadata not available after %v", err.Timeout)
+	if err.Err != nil {
+		return msg + ": " + err.Err.Error()
+	}
+	if len(err.MissingPodAnnotations) > 0 {
+		msg += "; missing pod annotations: " + strings.Join(err.MissingPodAnnotations, ", ")
+	}
+	if len(err.MissingAppAnnotations) > 0 {
+		msg += "; missing app annotations: " + strings.Join(err.MissingAppAnnotations, ", ")
+	}
+	return msg
+}
+
+// Flush drops all metadata cached by the client, so that it will be
+// fetched again from the metadata service, and restarts the retry
+// deadline.
+func (mdc *MDClient) Flush() {
+	mdc.ResetDeadline()
+	mdc.uuid = ""
+	mdc.podAnnotations = nil
+	mdc.podManifestJSON = nil
+	mdc.podManifest = nil
+	mdc.apps = nil
+}
+
+// Wait polls the metadata service every interval until it responds
+// and all listed pod and app annotations exist. If this doesn't happen
+// within timeout, a *WaitError is returned. Single requests are not
+// retried while waiting.
+func (mdc *MDClient) Wait(timeout, interval time.Duration, podAnnotations, appAnnotations []string) error {
+	retry := mdc.retry
+	mdc.retry.Deadline = 0
+	defer func() { mdc.retry = retry }()
+
+	deadline := time.Now().Add(timeout)
+	for {
+		mdc.Flush()
+		werr := mdc.checkAvailable(podAnnotations, appAnnotations)
+		if werr == nil {
+			return nil
+		}
+		if time.Now().Add(interval).After(deadline) {
+			werr.Timeout = timeout
+			return werr
+		}
+		if werr.Err != nil {
+			mdc.logf("waiting for metadata: %v", werr.Err)
+		} else {
+			mdc.logf("waiting for annotations: pod %v, app %v", werr.MissingPodAnnotations, werr.MissingAppAnnotations)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// checkAvailable returns nil if metadata service responds and all
+// listed annotations exist, or a *WaitError describing what's missing
+func (mdc *MDClient) checkAvailable(podAnnotations, appAnnotations []string) *WaitError {
+	if _, err := mdc.UUID(); err != nil {
+		return &WaitError{Err: err}
+	}
+
+	werr := &WaitError{}
+
+	if len(podAnnotations) > 0 {
+		anns, err := mdc.PodAnnotations()
+		if err != nil {
+			return &WaitError{Err: err}
+		}
+		werr.MissingPodAnnotations = missingAnnotations(anns, podAnnotations)
+	}
+
+	if len(appAnnotations) > 0 {
+		anns, err := mdc.AppAnnotations()
+		if err != nil {
+			return &WaitError{Err: err}
+		}
+		werr.MissingAppAnnotations = missingAnnotations(anns, appAnnotations)
+	}
+
+	if len(werr.MissingPodAnnotations) == 0 && len(werr.MissingAppAnnotations) == 0 {
+		return nil
+	}
+	return werr
+}
+
+func missingAnnotations(anns types.Annotations, names []string) []string {
+	var missing []string
+	for _, name := range names {
+		if _, found := anns.Get(name); !found {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,73 @@
+package mdc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// lateAnnotationServer serves metadata, but the "ready" pod annotation
+// appears only after n requests for pod annotations
+func lateAnnotationServer(n int) *httptest.Server {
+	requests := 0
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/acMetadata/v1/pod/annotations" {
+			requests++
+			if requests > n {
+				w.Write([]byte(`[{"name": "ip-address", "value": "10.1.2.3"}, {"name": "ready", "value": "yes"}]`))
+				return
+			}
+		}
+		serveMetadata(w, r)
+	}))
+}
+
+func TestWait(t *testing.T) {
+	srv := lateAnnotationServer(2)
+	defer srv.Close()
+
+	mdc, err := NewMDClient(Options{MetadataURL: srv.URL, AppName: "reduce-worker", Retry: testRetryPolicy})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mdc.Wait(time.Second, time.Millisecond, []string{"ready", "ip-address"}, []string{"foo"}); err != nil {
+		t.Error("Wait:", err)
+	}
+
+	if val, err := mdc.PodAnnotation("ready"); err != nil {
+		t.Error("PodAnnotation:", err)
+	} else if val != "yes" {
+		t.Error("Invalid annotation value:", val)
+	}
+}
+
+func TestWaitTimeout(t *testing.T) {
+	mdc := newTestClient(t)
+
+	err := mdc.Wait(10*time.Millisecond, time.Millisecond, []string{"ready", "ip-address"}, []string{"foo", "bar"})
+	if werr, ok := err.(*WaitError); !ok {
+		t.Error("Expected WaitError, got:", err)
+	} else {
+		if !reflect.DeepEqual(werr.MissingPodAnnotations, []string{"ready"}) {
+			t.Error("Invalid missing pod annotations:", werr.MissingPodAnnotations)
+		}
+		if !reflect.DeepEqual(werr.MissingAppAnnotations, []string{"bar"}) {
+			t.Error("Invalid missing app annotations:", werr.MissingAppAnnotations)
+		}
+	}
+
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	mdc, err = NewMDClient(Options{MetadataURL: srv.URL, AppName: "reduce-worker"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mdc.Wait(10*time.Millisecond, time.Millisecond, nil, nil); err == nil {
+		t.Error("Waiting for closed server didn't fail")
+	} else if werr, ok := err.(*WaitError); !ok || werr.Err == nil {
+		t.Error("Expected WaitError with connection error, got:", err)
+	}
+}
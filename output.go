@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v2"
+)
+
+// outputFormat is set with the global -output option: "text" (default),
+// "json", or "yaml"
+var outputFormat = "text"
+
+// outputFormatFlag is a flag.Value setting outputFormat
+type outputFormatFlag struct{}
+
+func (outputFormatFlag) String() string {
+	return outputFormat
+}
+
+func (outputFormatFlag) Set(value string) error {
+	switch value {
+	case "text", "json", "yaml":
+		outputFormat = value
+		return nil
+	}
+	return fmt.Errorf("invalid output format %q (expected text, json, or yaml)", value)
+}
+
+// structuredOutput returns true if commands print JSON or YAML
+// documents instead of text
+func structuredOutput() bool {
+	return outputFormat != "text"
+}
+
+// printDocument prints doc as JSON or YAML, depending on output
+// format. YAML is converted from JSON, so both have the same field
+// names (from JSON tags); object keys are sorted.
+func printDocument(doc interface{}) {
+	data, err := json.MarshalIndent(doc, "", "  ")
+	check(err)
+	if outputFormat == "yaml" {
+		var v interface{}
+		check(yaml.Unmarshal(data, &v))
+		data, err = yaml.Marshal(v)
+		check(err)
+		os.Stdout.Write(data)
+		return
+	}
+	fmt.Println(string(data))
+}
+
+// printResult prints doc in JSON or YAML output format, or calls text
+// to print plain text
+func printResult(doc interface{}, text func()) {
+	if structuredOutput() {
+		printDocument(doc)
+	} else {
+		text()
+	}
+}
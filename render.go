@@ -0,0 +1,385 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/template"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/3ofcoins/appc-metadata-client/mdc"
+)
+
+// renderSpec describes a single file rendered by render-all
+type renderSpec struct {
+	Source      string `yaml:"source"`
+	Destination string `yaml:"destination"`
+	Mode        string `yaml:"mode"`
+	Owner       string `yaml:"owner"`
+}
+
+// loadRenderSpecs reads render-all spec file (YAML or JSON). Relative
+// template source paths are relative to the spec file's directory.
+func loadRenderSpecs(path string) ([]renderSpec, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var specs []renderSpec
+	if err := yaml.Unmarshal(data, &specs); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	for i, spec := range specs {
+		if spec.Source == "" || spec.Destination == "" {
+			return nil, fmt.Errorf("%s: entry %d: source and destination are required", path, i+1)
+		}
+		if !filepath.IsAbs(spec.Source) {
+			specs[i].Source = filepath.Join(filepath.Dir(path), spec.Source)
+		}
+	}
+
+	return specs, nil
+}
+
+// fileMode parses octal file mode; 0644 if empty
+func fileMode(mode string) (os.FileMode, error) {
+	if mode == "" {
+		return 0644, nil
+	}
+	m, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || m&^uint64(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky) != 0 {
+		return 0, fmt.Errorf("invalid file mode: %q", mode)
+	}
+	return os.FileMode(m), nil
+}
+
+// fileOwner parses USER[:GROUP] (names or numeric IDs) to uid and gid;
+// -1 means not to change. If only user name is given, group is set to
+// user's primary group.
+func fileOwner(owner string) (uid, gid int, err error) {
+	uid, gid = -1, -1
+	if owner == "" {
+		return
+	}
+
+	pieces := strings.SplitN(owner, ":", 2)
+
+	if pieces[0] != "" {
+		if uid, err = strconv.Atoi(pieces[0]); err != nil {
+			var u *user.User
+			if u, err = user.Lookup(pieces[0]); err != nil {
+				return
+			}
+			uid, _ = strconv.Atoi(u.Uid)
+			if len(pieces) == 1 {
+				gid, _ = strconv.Atoi(u.Gid)
+			}
+		}
+	}
+
+	if len(pieces) == 2 && pieces[1] != "" {
+		if gid, err = strconv.Atoi(pieces[1]); err != nil {
+			var g *user.Group
+			if g, err = user.LookupGroup(pieces[1]); err != nil {
+				return
+			}
+			gid, _ = strconv.Atoi(g.Gid)
+		}
+	}
+
+	return uid, gid, nil
+}
+
+// outputFile is a file written by render commands
+type outputFile struct {
+	Path     string
+	Mode     os.FileMode
+	UID, GID int
+}
+
+func newOutputFile(path, mode, owner string) (*outputFile, error) {
+	of := &outputFile{Path: path}
+	var err error
+	if of.Mode, err = fileMode(mode); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if of.UID, of.GID, err = fileOwner(owner); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return of, nil
+}
+
+// fixAttrs sets mode and owner of existing file, if they differ from
+// requested ones, and reports whether anything has been changed.
+func (of *outputFile) fixAttrs() (changed bool, err error) {
+	fi, err := os.Stat(of.Path)
+	if err != nil {
+		return false, err
+	}
+	if fi.Mode()&^os.ModeType != of.Mode {
+		if err := os.Chmod(of.Path, of.Mode); err != nil {
+			return false, err
+		}
+		changed = true
+	}
+	if of.UID != -1 || of.GID != -1 {
+		st := fi.Sys().(*syscall.Stat_t)
+		if of.UID != -1 && int(st.Uid) != of.UID || of.GID != -1 && int(st.Gid) != of.GID {
+			if err := os.Chown(of.Path, of.UID, of.GID); err != nil {
+				return changed, err
+			}
+			changed = true
+		}
+	}
+	return changed, nil
+}
+
+// Write atomically replaces the file with data: data is written to
+// a temporary file in the same directory, which is synced and renamed
+// into place. If the file already has exactly the same contents, it is
+// not replaced, only its mode and owner are fixed if needed; true is
+// returned only if anything has changed.
+func (of *outputFile) Write(data []byte) (changed bool, err error) {
+	if old, err := ioutil.ReadFile(of.Path); err == nil && bytes.Equal(old, data) {
+		return of.fixAttrs()
+	}
+
+	dir, base := filepath.Split(of.Path)
+	if dir == "" {
+		dir = "."
+	}
+
+	tmp, err := ioutil.TempFile(dir, "."+base+".")
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		return false, err
+	}
+	if err = tmp.Chmod(of.Mode); err != nil {
+		return false, err
+	}
+	if of.UID != -1 || of.GID != -1 {
+		if err = tmp.Chown(of.UID, of.GID); err != nil {
+			return false, err
+		}
+	}
+	if err = tmp.Sync(); err != nil {
+		return false, err
+	}
+	if err = tmp.Close(); err != nil {
+		return false, err
+	}
+	if err = os.Rename(tmp.Name(), of.Path); err != nil {
+		return false, err
+	}
+
+	// Sync the directory, so that the rename is durable; not all
+	// systems support it, so errors are ignored.
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+
+	return true, nil
+}
+
+// renderResult reports whether a rendered file has been updated or
+// was unchanged
+type renderResult struct {
+	Path    string `json:"path"`
+	Changed bool   `json:"changed"`
+}
+
+func (rr renderResult) String() string {
+	if rr.Changed {
+		return rr.Path + ": updated"
+	}
+	return rr.Path + ": unchanged"
+}
+
+// parseTemplates parses template files at paths ("-" is standard
+// input) into a single set, named by file's base name. The first file
+// is the set's main template. Files included by templates are looked
+// up in each template's directory, and then in searchPath.
+func parseTemplates(paths, searchPath []string) (*template.Template, error) {
+	var dirs []string
+	for _, path := range paths {
+		if path != "-" {
+			dirs = append(dirs, filepath.Dir(path))
+		}
+	}
+
+	var tmpl *template.Template
+	for _, path := range paths {
+		var data []byte
+		var err error
+		name := filepath.Base(path)
+		if path == "-" {
+			name = "stdin"
+			data, err = ioutil.ReadAll(os.Stdin)
+		} else {
+			data, err = ioutil.ReadFile(path)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if tmpl == nil {
+			tmpl = mdc.NewTemplate(name, append(dirs, searchPath...)...)
+		}
+		t := tmpl
+		if name != tmpl.Name() {
+			t = tmpl.New(name)
+		}
+		if _, err := t.Parse(string(data)); err != nil {
+			return nil, err
+		}
+	}
+	return tmpl, nil
+}
+
+// renderedFile is a rendered template, not written yet
+type renderedFile struct {
+	*outputFile
+	data []byte
+}
+
+// renderFiles renders all templates listed in specs with a shared
+// client, without writing anything.
+func renderFiles(client *mdc.MDClient, specs []renderSpec, searchPath []string) ([]renderedFile, error) {
+	rendered := make([]renderedFile, len(specs))
+	for i, spec := range specs {
+		of, err := newOutputFile(spec.Destination, spec.Mode, spec.Owner)
+		if err != nil {
+			return nil, err
+		}
+
+		tmpl, err := parseTemplates([]string{spec.Source}, searchPath)
+		if err != nil {
+			return nil, err
+		}
+		buf := &bytes.Buffer{}
+		if err := tmpl.Execute(buf, client); err != nil {
+			return nil, err
+		}
+		rendered[i] = renderedFile{of, buf.Bytes()}
+	}
+	return rendered, nil
+}
+
+// writeFiles writes rendered templates to their destinations
+func writeFiles(rendered []renderedFile) ([]renderResult, error) {
+	results := make([]renderResult, len(rendered))
+	for i, rf := range rendered {
+		changed, err := rf.Write(rf.data)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = renderResult{rf.Path, changed}
+	}
+	return results, nil
+}
+
+// renderAll renders all templates listed in specs with a shared
+// client. Nothing is written unless all templates render successfully.
+func renderAll(client *mdc.MDClient, specs []renderSpec, searchPath []string) ([]renderResult, error) {
+	rendered, err := renderFiles(client, specs, searchPath)
+	if err != nil {
+		return nil, err
+	}
+	return writeFiles(rendered)
+}
+
+// renderAllCommand implements the render-all command
+func renderAllCommand(client *mdc.MDClient, args []string) {
+	var searchPath stringsFlag
+	fs := flag.NewFlagSet("render-all", flag.ExitOnError)
+	fs.Var(&searchPath, "I", "look up included templates in `DIR` (may be repeated)")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		usage(1)
+	}
+
+	specs, err := loadRenderSpecs(fs.Arg(0))
+	check(err)
+	results, err := renderAll(client, specs, searchPath)
+	check(err)
+	printResult(results, func() {
+		for _, rr := range results {
+			fmt.Println(rr)
+		}
+	})
+}
+
+// renderCommand implements the render and expand commands. Template
+// is rendered to memory first, so that nothing is written if it fails.
+func renderCommand(client *mdc.MDClient, args []string) {
+	var searchPath stringsFlag
+	fs := flag.NewFlagSet(args[0], flag.ExitOnError)
+	output := fs.String("o", "", "write output atomically to `PATH` instead of stdout")
+	mode := fs.String("mode", "", "octal file `MODE` of output file (default 0644)")
+	owner := fs.String("owner", "", "`USER[:GROUP]` owning output file")
+	fs.Var(&searchPath, "I", "look up included templates in `DIR` (may be repeated)")
+	name := fs.String("t", "", "render template `NAME` from the parsed files instead of the first file")
+	strict := fs.Bool("strict", false, "fail listing all missing annotations referenced by the template")
+	fs.Parse(args[1:])
+
+	if fs.NArg() < 1 || (args[0] == "expand" && fs.NArg() != 1) {
+		usage(1)
+	}
+
+	var tmpl *template.Template
+	var err error
+	if args[0] == "expand" {
+		tmpl, err = mdc.NewTemplate("", searchPath...).Parse(fs.Arg(0))
+	} else {
+		tmpl, err = parseTemplates(fs.Args(), searchPath)
+	}
+	check(err)
+
+	buf := &bytes.Buffer{}
+	execute := func() error {
+		if *name != "" {
+			return tmpl.ExecuteTemplate(buf, *name, client)
+		}
+		return tmpl.Execute(buf, client)
+	}
+	if *strict {
+		check(client.Strict(execute))
+	} else {
+		check(execute())
+	}
+
+	if *output == "" {
+		printResult(map[string]string{"output": buf.String()}, func() {
+			os.Stdout.Write(buf.Bytes())
+		})
+		return
+	}
+
+	of, err := newOutputFile(*output, *mode, *owner)
+	check(err)
+	changed, err := of.Write(buf.Bytes())
+	check(err)
+	rr := renderResult{of.Path, changed}
+	printResult(rr, func() { fmt.Println(rr) })
+}
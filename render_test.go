@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/appc/spec/schema"
+
+	"github.com/3ofcoins/appc-metadata-client/mdc"
+)
+
+const testPodManifest = `{
+    "acVersion": "0.7.4",
+    "acKind": "PodManifest",
+    "apps": [
+        {
+            "name": "reduce-worker",
+            "image": {
+                "name": "example.com/reduce-worker",
+                "id": "sha512-8d3fffddf79e9a232ffd19f9ccaa4d6b37a6a243dbe0f23137b108a043d9da13121a9b505c804956b22e93c7f93969f4a7ba8ddea45bf4aab0bebc8f814e0990"
+            },
+            "annotations": [{"name": "foo", "value": "baz"}]
+        }
+    ],
+    "annotations": [
+        {"name": "ip-address", "value": "10.1.2.3"},
+        {"name": "postgresql/host", "value": "db.example.com"},
+        {"name": "postgresql/port", "value": "5432"}
+    ]
+}`
+
+func newTestClient(t *testing.T) *mdc.MDClient {
+	pm := &schema.PodManifest{}
+	if err := json.Unmarshal([]byte(testPodManifest), pm); err != nil {
+		t.Fatal(err)
+	}
+	client, err := mdc.NewMDClient(mdc.Options{
+		AppName: "reduce-worker",
+		Service: &mdc.Pod{UUID: mdc.DefaultPodUUID, Manifest: pm},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "mdc-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	for name, contents := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRenderAll(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	writeTestFiles(t, dir, map[string]string{
+		"db.conf.tmpl":  `host={{.PodAnnotation "postgresql/host"}} port={{.PodAnnotation "postgresql/port"}}`,
+		"app.conf.tmpl": `foo={{.AppAnnotation "foo"}}`,
+		"spec.yml": `
+- source: db.conf.tmpl
+  destination: ` + filepath.Join(dir, "db.conf") + `
+  mode: 0600
+- source: app.conf.tmpl
+  destination: ` + filepath.Join(dir, "app.conf") + `
+`,
+	})
+
+	specs, err := loadRenderSpecs(filepath.Join(dir, "spec.yml"))
+	if err != nil {
+		t.Fatal("loadRenderSpecs:", err)
+	}
+
+	if len(specs) != 2 || specs[0].Source != filepath.Join(dir, "db.conf.tmpl") || specs[0].Mode != "0600" {
+		t.Errorf("Invalid specs: %#v", specs)
+	}
+
+	if _, err := renderAll(newTestClient(t), specs, nil); err != nil {
+		t.Fatal("renderAll:", err)
+	}
+
+	for name, expected := range map[string]string{
+		"db.conf":  "host=db.example.com port=5432",
+		"app.conf": "foo=baz",
+	} {
+		if data, err := ioutil.ReadFile(filepath.Join(dir, name)); err != nil {
+			t.Error(err)
+		} else if string(data) != expected {
+			t.Errorf("%s: got %#v, expected %#v", name, string(data), expected)
+		}
+	}
+
+	if fi, err := os.Stat(filepath.Join(dir, "db.conf")); err != nil {
+		t.Error(err)
+	} else if fi.Mode() != 0600 {
+		t.Error("Invalid db.conf mode:", fi.Mode())
+	}
+}
+
+func TestRenderAllFailsBeforeWriting(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	writeTestFiles(t, dir, map[string]string{
+		"good.tmpl": `foo={{.AppAnnotation "foo"}}`,
+		"bad.tmpl":  `bar={{.MustAppAnnotation "bar"}}`,
+		"spec.json": `[
+            {"source": "good.tmpl", "destination": "` + filepath.Join(dir, "good") + `"},
+            {"source": "bad.tmpl", "destination": "` + filepath.Join(dir, "bad") + `"}
+        ]`,
+	})
+
+	specs, err := loadRenderSpecs(filepath.Join(dir, "spec.json"))
+	if err != nil {
+		t.Fatal("loadRenderSpecs:", err)
+	}
+
+	if _, err := renderAll(newTestClient(t), specs, nil); err == nil {
+		t.Error("Rendering nonexistent MustAppAnnotation didn't fail")
+	}
+
+	for _, name := range []string{"good", "bad"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s: expected file not to exist, got: %v", name, err)
+		}
+	}
+}
+
+func TestParseTemplates(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	shared := filepath.Join(dir, "shared")
+	if err := os.MkdirAll(filepath.Join(shared, "common"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	writeTestFiles(t, dir, map[string]string{
+		"main.tmpl":    "{{include \"header.tmpl\" .}}\ndb:{{include \"db\" . | nindent 2}}\n{{include \"common/footer.tmpl\" .}}",
+		"header.tmpl":  `# pod {{.UUID}}`,
+		"helpers.tmpl": `{{define "db"}}host: {{.PodAnnotation "postgresql/host"}}` + "\n" + `port: {{.PodAnnotation "postgresql/port"}}{{end}}`,
+	})
+	writeTestFiles(t, shared, map[string]string{
+		"common/footer.tmpl": `# end`,
+		"header.tmpl":        `# shadowed by template's directory`,
+	})
+
+	tmpl, err := parseTemplates([]string{filepath.Join(dir, "main.tmpl"), filepath.Join(dir, "helpers.tmpl")}, []string{shared})
+	if err != nil {
+		t.Fatal("parseTemplates:", err)
+	}
+	if tmpl.Name() != "main.tmpl" {
+		t.Error("Invalid main template:", tmpl.Name())
+	}
+
+	client := newTestClient(t)
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, client); err != nil {
+		t.Error("Execute:", err)
+	} else if expected := "# pod " + mdc.DefaultPodUUID + "\ndb:\n  host: db.example.com\n  port: 5432\n# end"; buf.String() != expected {
+		t.Errorf("got %#v, expected %#v", buf.String(), expected)
+	}
+
+	buf.Reset()
+	if err := tmpl.ExecuteTemplate(buf, "db", client); err != nil {
+		t.Error("ExecuteTemplate:", err)
+	} else if buf.String() != "host: db.example.com\nport: 5432" {
+		t.Errorf("got %#v", buf.String())
+	}
+
+	tmpl, err = parseTemplates([]string{filepath.Join(dir, "header.tmpl")}, nil)
+	if err != nil {
+		t.Fatal("parseTemplates:", err)
+	}
+	if err := tmpl.Execute(buf, client); err != nil {
+		t.Error("Execute:", err)
+	}
+	if _, err := parseTemplates([]string{filepath.Join(dir, "nonexistent.tmpl")}, nil); err == nil {
+		t.Error("Expected error for nonexistent file")
+	}
+}
+
+func TestFileModeAndOwner(t *testing.T) {
+	if mode, err := fileMode(""); err != nil || mode != 0644 {
+		t.Error("Invalid default mode:", mode, err)
+	}
+	if mode, err := fileMode("0755"); err != nil || mode != 0755 {
+		t.Error("Invalid mode:", mode, err)
+	}
+	if _, err := fileMode("rwxr-xr-x"); err == nil {
+		t.Error("Invalid mode didn't fail")
+	}
+
+	if uid, gid, err := fileOwner(""); err != nil || uid != -1 || gid != -1 {
+		t.Error("Invalid empty owner:", uid, gid, err)
+	}
+	if uid, gid, err := fileOwner("100:300"); err != nil || uid != 100 || gid != 300 {
+		t.Error("Invalid owner:", uid, gid, err)
+	}
+	if uid, gid, err := fileOwner(":300"); err != nil || uid != -1 || gid != 300 {
+		t.Error("Invalid group-only owner:", uid, gid, err)
+	}
+}
+
+func TestOutputFile(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	of, err := newOutputFile(filepath.Join(dir, "app.conf"), "0640", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if changed, err := of.Write([]byte("foo=bar\n")); err != nil {
+		t.Fatal("Write:", err)
+	} else if !changed {
+		t.Error("New file reported unchanged")
+	}
+
+	fi, err := os.Stat(of.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode() != 0640 {
+		t.Error("Invalid file mode:", fi.Mode())
+	}
+
+	if changed, err := of.Write([]byte("foo=bar\n")); err != nil {
+		t.Fatal("Write:", err)
+	} else if changed {
+		t.Error("Identical file reported changed")
+	}
+
+	if fi2, err := os.Stat(of.Path); err != nil {
+		t.Fatal(err)
+	} else if !os.SameFile(fi, fi2) {
+		t.Error("Identical file has been replaced")
+	}
+
+	// Mode of identical file is fixed in place
+	of.Mode = 0600
+	if changed, err := of.Write([]byte("foo=bar\n")); err != nil {
+		t.Fatal("Write:", err)
+	} else if !changed {
+		t.Error("File with fixed mode reported unchanged")
+	}
+	if fi2, err := os.Stat(of.Path); err != nil {
+		t.Fatal(err)
+	} else if !os.SameFile(fi, fi2) {
+		t.Error("Identical file has been replaced")
+	} else if fi2.Mode() != 0600 {
+		t.Error("Invalid file mode:", fi2.Mode())
+	}
+
+	// Owner is already right
+	of.UID, of.GID = os.Getuid(), os.Getgid()
+	if changed, err := of.Write([]byte("foo=bar\n")); err != nil {
+		t.Fatal("Write:", err)
+	} else if changed {
+		t.Error("File with right mode and owner reported changed")
+	}
+
+	if changed, err := of.Write([]byte("foo=baz\n")); err != nil {
+		t.Fatal("Write:", err)
+	} else if !changed {
+		t.Error("Changed file reported unchanged")
+	}
+
+	if data, err := ioutil.ReadFile(of.Path); err != nil {
+		t.Error(err)
+	} else if string(data) != "foo=baz\n" {
+		t.Errorf("Invalid file contents: %#v", string(data))
+	}
+
+	if entries, err := ioutil.ReadDir(dir); err != nil {
+		t.Error(err)
+	} else if len(entries) != 1 {
+		t.Error("Temporary files left behind:", entries)
+	}
+}
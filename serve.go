@@ -0,0 +1,77 @@
+package main
+
+import (
+	"crypto/rand"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/appc/spec/schema"
+
+	"github.com/3ofcoins/appc-metadata-client/mdc"
+)
+
+// randomUUID returns a random (version 4) UUID
+func randomUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%X-%X-%X-%X-%X", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+func serve(args []string) {
+	var imageManifests stringsFlag
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	listen := fs.String("listen", "127.0.0.1:18112", "listen on `ADDRESS`")
+	podManifest := fs.String("pod-manifest", "", "read pod manifest from `FILE`")
+	uuid := fs.String("uuid", "", "pod `UUID` (random if not given)")
+	fs.Var(&imageManifests, "image-manifest", "read image manifest of app `NAME=FILE` (may be repeated)")
+	fs.Parse(args)
+
+	if *podManifest == "" {
+		fmt.Fprintln(os.Stderr, "ERROR: -pod-manifest is required")
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	pm, err := mdc.LoadPodManifest(*podManifest)
+	check(err)
+
+	pod := &mdc.Pod{
+		UUID:           *uuid,
+		Manifest:       pm,
+		ImageManifests: make(map[string]*schema.ImageManifest),
+	}
+
+	if pod.UUID == "" {
+		pod.UUID = randomUUID()
+	}
+
+	for _, arg := range imageManifests {
+		pieces := strings.SplitN(arg, "=", 2)
+		if len(pieces) != 2 {
+			fatal(fmt.Errorf("-image-manifest %q: expected NAME=FILE", arg))
+		}
+		im, err := mdc.LoadImageManifest(pieces[1])
+		check(err)
+		pod.ImageManifests[pieces[0]] = im
+	}
+
+	for _, app := range pm.Apps {
+		if pod.ImageManifests[app.Name.String()] == nil {
+			fmt.Fprintf(os.Stderr, "WARNING: no image manifest for app %v\n", app.Name)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Serving metadata of pod %v at http://%v/\n", pod.UUID, *listen)
+	fmt.Fprintf(os.Stderr, "export AC_METADATA_URL=http://%v\n", *listen)
+	check(http.ListenAndServe(*listen, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logf("%v %v %v", r.RemoteAddr, r.Method, r.URL.Path)
+		pod.ServeHTTP(w, r)
+	})))
+}